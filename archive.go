@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"triplea-backend-assignment/config"
+	"triplea-backend-assignment/database"
+	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/repository"
+	"triplea-backend-assignment/service"
+)
+
+// runArchiveCommand handles the "archive" subcommand.
+func runArchiveCommand(ctx context.Context, cfg *config.Config, store *database.Store, args []string) error {
+	flags := flag.NewFlagSet("archive", flag.ContinueOnError)
+	before := flags.String("before", "", "archive partitions that end on or before this date (YYYY-MM-DD)")
+	dir := flags.String("dir", cfg.Partitions.ArchiveDir, "directory for the archive files")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *before == "" || flags.NArg() > 0 {
+		return errors.New("usage: archive -before YYYY-MM-DD [-dir DIR]")
+	}
+	cutoff, err := time.Parse(models.BusinessDateLayout, *before)
+	if err != nil {
+		return fmt.Errorf("before must be a date in YYYY-MM-DD format: %w", err)
+	}
+
+	archiver := service.NewTransactionArchiver(repository.NewPartitionRepository(store), *dir)
+	archived, err := archiver.ArchiveBefore(ctx, cutoff, time.Now())
+	printArchived(archived)
+	return err
+}
+
+func printArchived(archived []models.ArchivedPartition) {
+	if len(archived) == 0 {
+		fmt.Println("Nothing to archive")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PARTITION\tROWS\tFILE")
+	for _, partition := range archived {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", partition.Name, partition.Rows, partition.File)
+	}
+	w.Flush()
+}
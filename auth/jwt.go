@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"triplea-backend-assignment/config"
+	"triplea-backend-assignment/models"
+)
+
+// userScopes are the scopes a user token may grant. Any other scope in the
+// token, admin in particular, is ignored: administration is only possible
+// with an API key.
+var userScopes = map[string]bool{
+	models.ScopeAccountsRead:   true,
+	models.ScopeAccountsWrite:  true,
+	models.ScopeTransfersWrite: true,
+}
+
+// JWTVerifier verifies the bearer tokens of end users and maps their claims
+// to a principal: sub is the subject, the space-separated scope claim the
+// scopes, and the configured accounts claim the accounts the user owns.
+type JWTVerifier struct {
+	parser        *jwt.Parser
+	hmacSecret    []byte
+	rsaKey        *rsa.PublicKey
+	jwks          map[string]*rsa.PublicKey
+	accountsClaim string
+}
+
+func NewJWTVerifier(cfg config.JWTConfig) (*JWTVerifier, error) {
+	v := &JWTVerifier{accountsClaim: cfg.AccountsClaim}
+	var methods []string
+	if cfg.HS256Secret != "" {
+		v.hmacSecret = []byte(cfg.HS256Secret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.RS256PublicKeyFile != "" {
+		pem, err := os.ReadFile(cfg.RS256PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT public key: %w", err)
+		}
+		v.rsaKey, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JWT public key: %w", err)
+		}
+	}
+	if cfg.JWKSFile != "" {
+		jwks, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.jwks = jwks
+	}
+	if v.rsaKey != nil || len(v.jwks) > 0 {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("no JWT verification key configured")
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(cfg.Leeway),
+		jwt.WithJSONNumber(),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(options...)
+	return v, nil
+}
+
+// Verify checks the signature and the time, issuer and audience claims of
+// token and returns its user principal, with only the scopes a user may
+// have.
+func (v *JWTVerifier) Verify(token string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(token, claims, v.key); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, errors.New("invalid token: sub claim is required")
+	}
+	principal := &Principal{Kind: KindUser, Subject: subject}
+	if scope, ok := claims["scope"].(string); ok {
+		for _, granted := range strings.Fields(scope) {
+			if userScopes[granted] {
+				principal.Scopes = append(principal.Scopes, granted)
+			}
+		}
+	}
+	if raw, ok := claims[v.accountsClaim]; ok {
+		principal.AccountIDs, err = accountIDs(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token: %s claim: %w", v.accountsClaim, err)
+		}
+	}
+	return principal, nil
+}
+
+func (v *JWTVerifier) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return v.hmacSecret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := v.jwks[kid]; ok {
+			return key, nil
+		}
+		if v.rsaKey != nil {
+			return v.rsaKey, nil
+		}
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	default:
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+}
+
+// accountIDs reads a list of account IDs given as JSON numbers or strings.
+func accountIDs(raw interface{}) ([]int64, error) {
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, errors.New("must be a list of account IDs")
+	}
+	ids := make([]int64, 0, len(list))
+	for _, item := range list {
+		var text string
+		switch value := item.(type) {
+		case json.Number:
+			text = value.String()
+		case string:
+			text = value
+		default:
+			return nil, fmt.Errorf("account ID %v is not a number", item)
+		}
+		id, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("account ID %q is not an integer", text)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// loadJWKS reads the RSA signing keys of a JWKS file, by key ID. Keys of
+// other types or uses are skipped.
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	var set jsonWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS file: %w", err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %q: invalid modulus: %w", jwk.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %q: invalid exponent: %w", jwk.Kid, err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("JWKS key %q: invalid exponent", jwk.Kid)
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS file has no RSA signing keys")
+	}
+	return keys, nil
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"triplea-backend-assignment/config"
+)
+
+const testHS256Secret = "hs256-secret-for-tests-0123456789abcdef"
+
+func TestJWTVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+	dir := t.TempDir()
+	pemFile := writePublicKeyPEM(t, dir, &rsaKey.PublicKey)
+	jwksFile := writeJWKS(t, dir, map[string]*rsa.PublicKey{"key-1": &rsaKey.PublicKey})
+
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":         "alice",
+			"iss":         "https://id.example.com",
+			"aud":         "transfers-api",
+			"iat":         now.Unix(),
+			"exp":         now.Add(time.Hour).Unix(),
+			"scope":       "accounts:read transfers:write",
+			"account_ids": []interface{}{1, "9007199254740993"},
+		}
+	}
+	with := func(change func(jwt.MapClaims)) jwt.MapClaims {
+		claims := valid()
+		change(claims)
+		return claims
+	}
+	hs256 := func(claims jwt.MapClaims) string {
+		return sign(t, jwt.SigningMethodHS256, claims, nil, []byte(testHS256Secret))
+	}
+	rs256 := func(claims jwt.MapClaims, kid string, key *rsa.PrivateKey) string {
+		return sign(t, jwt.SigningMethodRS256, claims, map[string]interface{}{"kid": kid}, key)
+	}
+
+	base := config.JWTConfig{Issuer: "https://id.example.com", Audience: "transfers-api", AccountsClaim: "account_ids"}
+	hsConfig, pemConfig, jwksConfig := base, base, base
+	hsConfig.HS256Secret = testHS256Secret
+	pemConfig.RS256PublicKeyFile = pemFile
+	jwksConfig.JWKSFile = jwksFile
+
+	tests := []struct {
+		name    string
+		cfg     config.JWTConfig
+		token   string
+		wantErr bool
+	}{
+		{name: "HS256", cfg: hsConfig, token: hs256(valid())},
+		{name: "RS256 with PEM key", cfg: pemConfig, token: rs256(valid(), "", rsaKey)},
+		{name: "RS256 with JWKS", cfg: jwksConfig, token: rs256(valid(), "key-1", rsaKey)},
+		{name: "RS256 with unknown kid", cfg: jwksConfig, token: rs256(valid(), "key-2", rsaKey), wantErr: true},
+		{name: "RS256 signed by another key", cfg: jwksConfig, token: rs256(valid(), "key-1", otherKey), wantErr: true},
+		{name: "HS256 when only RS256 is configured", cfg: pemConfig, token: hs256(valid()), wantErr: true},
+		{name: "unsigned", cfg: hsConfig, token: sign(t, jwt.SigningMethodNone, valid(), nil, jwt.UnsafeAllowNoneSignatureType), wantErr: true},
+		{name: "expired", cfg: hsConfig, token: hs256(with(func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() })), wantErr: true},
+		{name: "no expiry", cfg: hsConfig, token: hs256(with(func(c jwt.MapClaims) { delete(c, "exp") })), wantErr: true},
+		{name: "wrong issuer", cfg: hsConfig, token: hs256(with(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" })), wantErr: true},
+		{name: "wrong audience", cfg: hsConfig, token: hs256(with(func(c jwt.MapClaims) { c["aud"] = "other-api" })), wantErr: true},
+		{name: "no subject", cfg: hsConfig, token: hs256(with(func(c jwt.MapClaims) { delete(c, "sub") })), wantErr: true},
+		{name: "admin and unknown scopes dropped", cfg: hsConfig,
+			token: hs256(with(func(c jwt.MapClaims) { c["scope"] = "admin accounts:read payouts:write transfers:write" }))},
+		{name: "malformed accounts claim", cfg: hsConfig, token: hs256(with(func(c jwt.MapClaims) { c["account_ids"] = "1,2" })), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier, err := NewJWTVerifier(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewJWTVerifier() error = %v", err)
+			}
+			principal, err := verifier.Verify(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			want := &Principal{
+				Kind:       KindUser,
+				Subject:    "alice",
+				Scopes:     []string{"accounts:read", "transfers:write"},
+				AccountIDs: []int64{1, 9007199254740993},
+			}
+			if !reflect.DeepEqual(principal, want) {
+				t.Errorf("Verify() = %+v, want %+v", principal, want)
+			}
+		})
+	}
+}
+
+func TestNewJWTVerifierWithoutKeys(t *testing.T) {
+	if _, err := NewJWTVerifier(config.JWTConfig{AccountsClaim: "account_ids"}); err == nil {
+		t.Error("NewJWTVerifier() error = nil, want an error without keys")
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, claims jwt.MapClaims, header map[string]interface{}, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	for name, value := range header {
+		if value != "" {
+			token.Header[name] = value
+		}
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	return signed
+}
+
+func writePublicKeyPEM(t *testing.T, dir string, key *rsa.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey() error = %v", err)
+	}
+	path := filepath.Join(dir, "jwt.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+func writeJWKS(t *testing.T, dir string, keys map[string]*rsa.PublicKey) string {
+	t.Helper()
+	set := jsonWebKeySet{Keys: []jsonWebKey{{Kty: "EC", Kid: "ec-key"}}}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, jsonWebKey{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	path := filepath.Join(dir, "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+
+	"triplea-backend-assignment/models"
+)
+
+// Kinds of principal.
+const (
+	// KindService is a caller authenticated with an API key. It may act on
+	// any account its scopes allow.
+	KindService = "service"
+	// KindUser is an end user authenticated with a JWT. It may only act on
+	// the accounts it owns.
+	KindUser = "user"
+)
+
+// Principal is who a request is made by.
+type Principal struct {
+	Kind    string
+	Subject string
+	Scopes  []string
+	// AccountIDs are the accounts a user owns. They are not used for
+	// services.
+	AccountIDs []int64
+}
+
+// HasScope reports whether the principal may use a route that requires
+// scope. The admin scope of a service grants every other scope; users never
+// have it.
+func (p *Principal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if granted == models.ScopeAdmin && p.Kind != KindService {
+			continue
+		}
+		if granted == scope || granted == models.ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// OwnsAccount reports whether the principal may read or debit accountID.
+// Services may act on every account; users only on their own.
+func (p *Principal) OwnsAccount(accountID int64) bool {
+	if p.Kind == KindService {
+		return true
+	}
+	for _, id := range p.AccountIDs {
+		if id == accountID {
+			return true
+		}
+	}
+	return false
+}
+
+// FromAPIKey returns the service principal of key.
+func FromAPIKey(key *models.APIKey) *Principal {
+	return &Principal{Kind: KindService, Subject: "api_key:" + key.Prefix, Scopes: key.Scopes}
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the principal of the request, or nil if it was not
+// authenticated.
+func FromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"testing"
+
+	"triplea-backend-assignment/models"
+)
+
+func TestPrincipal_HasScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		kind   string
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{name: "granted", kind: KindService, scopes: []string{models.ScopeAccountsRead}, scope: models.ScopeAccountsRead, want: true},
+		{name: "not granted", kind: KindService, scopes: []string{models.ScopeAccountsRead}, scope: models.ScopeTransfersWrite, want: false},
+		{name: "admin grants everything", kind: KindService, scopes: []string{models.ScopeAdmin}, scope: models.ScopeTransfersWrite, want: true},
+		{name: "admin of a service", kind: KindService, scopes: []string{models.ScopeAdmin}, scope: models.ScopeAdmin, want: true},
+		{name: "admin of a user", kind: KindUser, scopes: []string{models.ScopeAdmin}, scope: models.ScopeAdmin, want: false},
+		{name: "admin of a user grants nothing else", kind: KindUser, scopes: []string{models.ScopeAdmin}, scope: models.ScopeAccountsRead, want: false},
+		{name: "no scopes", kind: KindService, scope: models.ScopeAccountsRead, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal := &Principal{Kind: tt.kind, Scopes: tt.scopes}
+			if got := principal.HasScope(tt.scope); got != tt.want {
+				t.Errorf("HasScope(%q) = %v, want %v", tt.scope, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrincipal_OwnsAccount(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		accountID int64
+		want      bool
+	}{
+		{name: "user owns account", principal: Principal{Kind: KindUser, AccountIDs: []int64{1, 2}}, accountID: 2, want: true},
+		{name: "user does not own account", principal: Principal{Kind: KindUser, AccountIDs: []int64{1, 2}}, accountID: 3, want: false},
+		{name: "user without accounts", principal: Principal{Kind: KindUser}, accountID: 1, want: false},
+		{name: "service acts on any account", principal: Principal{Kind: KindService}, accountID: 3, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.OwnsAccount(tt.accountID); got != tt.want {
+				t.Errorf("OwnsAccount(%d) = %v, want %v", tt.accountID, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,17 +2,48 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
+	Log        LogConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	Sweeper    SweeperConfig
+	Timeouts   TimeoutConfig
+	Partitions PartitionConfig
+	Tracing    TracingConfig
+	Auth       AuthConfig
+	RateLimits RateLimitConfig
+}
+
+type LogConfig struct {
+	Level slog.Level
 }
 
 type ServerConfig struct {
 	Host string
 	Port string
+	// ReadTimeout, WriteTimeout and IdleTimeout bound a connection. Keep
+	// WriteTimeout longer than the longest route timeout, or slow responses
+	// such as statement exports are cut off.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// ShutdownDelay is how long the server keeps serving after /readyz
+	// starts failing, so load balancers stop routing to it first.
+	// ShutdownGracePeriod is then how long SIGINT/SIGTERM waits for
+	// in-flight requests and background workers before the process exits
+	// anyway.
+	ShutdownDelay       time.Duration
+	ShutdownGracePeriod time.Duration
+	// ReadinessTimeout bounds the dependency checks of /readyz.
+	ReadinessTimeout time.Duration
 }
 
 type DatabaseConfig struct {
@@ -22,13 +53,304 @@ type DatabaseConfig struct {
 	Password string
 	DBName   string
 	SSLMode  string
+	// AutoMigrate applies pending migrations at startup. Disable it when
+	// migrations are run as a separate deployment step.
+	AutoMigrate bool
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// StatementTimeout and LockTimeout are set on every session, so a runaway
+	// query or a stuck lock fails instead of holding a connection forever.
+	StatementTimeout time.Duration
+	LockTimeout      time.Duration
+	// ConnectAttempts and ConnectBackoff control how long startup waits for
+	// the database. The backoff doubles after every failed attempt.
+	ConnectAttempts int
+	ConnectBackoff  time.Duration
+
+	// SlowQueryThreshold is how long a query may run before it is logged.
+	SlowQueryThreshold time.Duration
+
+	// ReplicaHosts lists read replicas as host or host:port. They share the
+	// primary's credentials and database name. Reads fall back to the primary
+	// while a replica is down or lags by more than ReplicaMaxLag.
+	ReplicaHosts         []string
+	ReplicaMaxLag        time.Duration
+	ReplicaCheckInterval time.Duration
+}
+
+type SweeperConfig struct {
+	Interval      time.Duration
+	PendingMaxAge time.Duration
+	BatchSize     int
+}
+
+// PartitionConfig controls the monthly partitions of the transactions table.
+// MonthsAhead partitions beyond the current month are kept ready, and the
+// archive command writes old partitions to ArchiveDir.
+type PartitionConfig struct {
+	MaintenanceInterval time.Duration
+	MonthsAhead         int
+	ArchiveDir          string
+}
+
+// TracingConfig selects where spans go: "none", "otlp" or "stdout". The
+// OTLP exporter takes its endpoint from the standard OTEL_EXPORTER_OTLP_*
+// variables. SampleRatio applies to traces that do not arrive with a
+// sampling decision.
+type TracingConfig struct {
+	Exporter    string
+	ServiceName string
+	SampleRatio float64
+}
+
+// AuthConfig holds the bootstrap admin key. It is accepted wherever the
+// admin scope is, so the first API keys can be created; leave it empty once
+// they exist.
+type AuthConfig struct {
+	AdminAPIKey string
+	JWT         JWTConfig
+	Signatures  SignatureConfig
+}
+
+// JWTConfig holds what user tokens are verified with. HS256Secret enables
+// HS256 tokens; RS256PublicKeyFile (PEM) or JWKSFile enable RS256 tokens,
+// the JWKS file picking the key by the token's kid. JWTs are rejected when
+// none is set. Issuer and Audience are checked when set. AccountsClaim names
+// the claim that lists the account IDs a user owns.
+type JWTConfig struct {
+	HS256Secret        string
+	RS256PublicKeyFile string
+	JWKSFile           string
+	Issuer             string
+	Audience           string
+	AccountsClaim      string
+	Leeway             time.Duration
+}
+
+// SignatureConfig holds the shared secrets of the internal clients that sign
+// their requests, by client ID. A signed request is rejected if its
+// timestamp is more than MaxClockSkew away from the server's clock.
+type SignatureConfig struct {
+	ClientSecrets map[string]string
+	MaxClockSkew  time.Duration
+}
+
+// Enabled reports whether any key to verify JWTs with is configured.
+func (c JWTConfig) Enabled() bool {
+	return c.HS256Secret != "" || c.RS256PublicKeyFile != "" || c.JWKSFile != ""
+}
+
+// minSecretLength keeps the bootstrap key and the HS256 secret as hard to
+// guess as generated API keys.
+const minSecretLength = 32
+
+// maxClientIDLength matches the request_nonces.client_id column.
+const maxClientIDLength = 64
+
+// TimeoutConfig bounds how long a request may run. Statements, reports and
+// business day closes scan whole tables and get their own limits.
+type TimeoutConfig struct {
+	Default          time.Duration
+	Statement        time.Duration
+	Report           time.Duration
+	BusinessDayClose time.Duration
+}
+
+// RateLimitConfig limits how often each client may call a route. Default
+// applies to every authenticated route not in Routes, which is keyed by
+// method and route template, e.g. "POST /transactions". PerIP limits all
+// requests from an IP address before they are authenticated, so that bad
+// credentials are throttled too. AccountDebits limits the transfers out of
+// each source account, whoever makes them.
+type RateLimitConfig struct {
+	Default       RateLimit
+	PerIP         RateLimit
+	Routes        map[string]RateLimit
+	AccountDebits RateLimit
+}
+
+// RateLimit allows Requests per Per, in bursts of up to Requests. The zero
+// value allows everything.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (l RateLimit) Enabled() bool {
+	return l.Requests > 0 && l.Per > 0
 }
 
 func LoadConfig() (*Config, error) {
+	logLevel, err := getEnvLogLevel("LOG_LEVEL", slog.LevelInfo)
+	if err != nil {
+		return nil, err
+	}
+	serverReadTimeout, err := getEnvDuration("SERVER_READ_TIMEOUT", 15*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	serverWriteTimeout, err := getEnvDuration("SERVER_WRITE_TIMEOUT", 3*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	serverIdleTimeout, err := getEnvDuration("SERVER_IDLE_TIMEOUT", 2*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	shutdownDelay, err := getEnvNonNegativeDuration("SERVER_SHUTDOWN_DELAY", 0)
+	if err != nil {
+		return nil, err
+	}
+	shutdownGracePeriod, err := getEnvDuration("SERVER_SHUTDOWN_GRACE_PERIOD", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	readinessTimeout, err := getEnvDuration("READINESS_TIMEOUT", 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	sweeperInterval, err := getEnvDuration("SWEEPER_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	sweeperPendingMaxAge, err := getEnvDuration("SWEEPER_PENDING_MAX_AGE", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	sweeperBatchSize, err := getEnvInt("SWEEPER_BATCH_SIZE", 100)
+	if err != nil {
+		return nil, err
+	}
+	autoMigrate, err := getEnvBool("DB_AUTO_MIGRATE", true)
+	if err != nil {
+		return nil, err
+	}
+	maxOpenConns, err := getEnvInt("DB_MAX_OPEN_CONNS", 25)
+	if err != nil {
+		return nil, err
+	}
+	maxIdleConns, err := getEnvInt("DB_MAX_IDLE_CONNS", 5)
+	if err != nil {
+		return nil, err
+	}
+	connMaxLifetime, err := getEnvDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	connMaxIdleTime, err := getEnvDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	dbStatementTimeout, err := getEnvDuration("DB_STATEMENT_TIMEOUT", 2*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	dbLockTimeout, err := getEnvDuration("DB_LOCK_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	connectAttempts, err := getEnvInt("DB_CONNECT_ATTEMPTS", 5)
+	if err != nil {
+		return nil, err
+	}
+	connectBackoff, err := getEnvDuration("DB_CONNECT_BACKOFF", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	slowQueryThreshold, err := getEnvNonNegativeDuration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	replicaMaxLag, err := getEnvDuration("DB_REPLICA_MAX_LAG", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	replicaCheckInterval, err := getEnvDuration("DB_REPLICA_CHECK_INTERVAL", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	partitionMaintenanceInterval, err := getEnvDuration("PARTITION_MAINTENANCE_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	partitionMonthsAhead, err := getEnvInt("PARTITION_MONTHS_AHEAD", 3)
+	if err != nil {
+		return nil, err
+	}
+	tracingSampleRatio, err := getEnvFloat("TRACING_SAMPLE_RATIO", 1)
+	if err != nil {
+		return nil, err
+	}
+	adminAPIKey := getEnv("ADMIN_API_KEY", "")
+	if adminAPIKey != "" && len(adminAPIKey) < minSecretLength {
+		return nil, fmt.Errorf("ADMIN_API_KEY must be at least %d characters", minSecretLength)
+	}
+	jwtSecret := getEnv("JWT_HS256_SECRET", "")
+	if jwtSecret != "" && len(jwtSecret) < minSecretLength {
+		return nil, fmt.Errorf("JWT_HS256_SECRET must be at least %d characters", minSecretLength)
+	}
+	jwtLeeway, err := getEnvDuration("JWT_LEEWAY", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	signatureClientSecrets, err := getEnvClientSecrets("HMAC_CLIENT_SECRETS")
+	if err != nil {
+		return nil, err
+	}
+	signatureMaxClockSkew, err := getEnvDuration("HMAC_MAX_CLOCK_SKEW", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	defaultRateLimit, err := getEnvRateLimit("RATE_LIMIT_DEFAULT", "100/s")
+	if err != nil {
+		return nil, err
+	}
+	routeRateLimits, err := getEnvRouteRateLimits("RATE_LIMIT_ROUTES")
+	if err != nil {
+		return nil, err
+	}
+	perIPRateLimit, err := getEnvRateLimit("RATE_LIMIT_PER_IP", "200/s")
+	if err != nil {
+		return nil, err
+	}
+	accountDebitRateLimit, err := getEnvRateLimit("RATE_LIMIT_ACCOUNT_DEBITS", "10/s")
+	if err != nil {
+		return nil, err
+	}
+	requestTimeout, err := getEnvDuration("REQUEST_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	statementTimeout, err := getEnvDuration("STATEMENT_TIMEOUT", 2*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	reportTimeout, err := getEnvDuration("REPORT_TIMEOUT", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	businessDayCloseTimeout, err := getEnvDuration("BUSINESS_DAY_CLOSE_TIMEOUT", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
 	config := &Config{
+		Log: LogConfig{
+			Level: logLevel,
+		},
 		Server: ServerConfig{
 			Host: getEnv("SERVER_HOST", "localhost"),
 			Port: getEnv("SERVER_PORT", "8080"),
+
+			ReadTimeout:         serverReadTimeout,
+			WriteTimeout:        serverWriteTimeout,
+			IdleTimeout:         serverIdleTimeout,
+			ShutdownDelay:       shutdownDelay,
+			ShutdownGracePeriod: shutdownGracePeriod,
+			ReadinessTimeout:    readinessTimeout,
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -37,24 +359,100 @@ func LoadConfig() (*Config, error) {
 			Password: getEnv("DB_PASSWORD", "postgres"),
 			DBName:   getEnv("DB_NAME", "transfers_db"),
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+
+			AutoMigrate: autoMigrate,
+
+			MaxOpenConns:     maxOpenConns,
+			MaxIdleConns:     maxIdleConns,
+			ConnMaxLifetime:  connMaxLifetime,
+			ConnMaxIdleTime:  connMaxIdleTime,
+			StatementTimeout: dbStatementTimeout,
+			LockTimeout:      dbLockTimeout,
+			ConnectAttempts:  connectAttempts,
+			ConnectBackoff:   connectBackoff,
+
+			SlowQueryThreshold: slowQueryThreshold,
+
+			ReplicaHosts:         getEnvList("DB_REPLICA_HOSTS"),
+			ReplicaMaxLag:        replicaMaxLag,
+			ReplicaCheckInterval: replicaCheckInterval,
+		},
+		Sweeper: SweeperConfig{
+			Interval:      sweeperInterval,
+			PendingMaxAge: sweeperPendingMaxAge,
+			BatchSize:     sweeperBatchSize,
+		},
+		Timeouts: TimeoutConfig{
+			Default:          requestTimeout,
+			Statement:        statementTimeout,
+			Report:           reportTimeout,
+			BusinessDayClose: businessDayCloseTimeout,
+		},
+		Partitions: PartitionConfig{
+			MaintenanceInterval: partitionMaintenanceInterval,
+			MonthsAhead:         partitionMonthsAhead,
+			ArchiveDir:          getEnv("ARCHIVE_DIR", "archive"),
+		},
+		Tracing: TracingConfig{
+			Exporter:    getEnv("TRACING_EXPORTER", "none"),
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "transfers-api"),
+			SampleRatio: tracingSampleRatio,
+		},
+		Auth: AuthConfig{
+			AdminAPIKey: adminAPIKey,
+			JWT: JWTConfig{
+				HS256Secret:        jwtSecret,
+				RS256PublicKeyFile: getEnv("JWT_RS256_PUBLIC_KEY_FILE", ""),
+				JWKSFile:           getEnv("JWT_JWKS_FILE", ""),
+				Issuer:             getEnv("JWT_ISSUER", ""),
+				Audience:           getEnv("JWT_AUDIENCE", ""),
+				AccountsClaim:      getEnv("JWT_ACCOUNTS_CLAIM", "account_ids"),
+				Leeway:             jwtLeeway,
+			},
+			Signatures: SignatureConfig{
+				ClientSecrets: signatureClientSecrets,
+				MaxClockSkew:  signatureMaxClockSkew,
+			},
+		},
+		RateLimits: RateLimitConfig{
+			Default:       defaultRateLimit,
+			PerIP:         perIPRateLimit,
+			Routes:        routeRateLimits,
+			AccountDebits: accountDebitRateLimit,
 		},
 	}
 
 	return config, nil
 }
 
+// GetDSN returns the connection string. lib/pq passes statement_timeout and
+// lock_timeout (in milliseconds) to the server as session settings.
 func (c *Config) GetDSN() string {
 	return fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s statement_timeout=%d lock_timeout=%d",
 		c.Database.Host,
 		c.Database.Port,
 		c.Database.User,
 		c.Database.Password,
 		c.Database.DBName,
 		c.Database.SSLMode,
+		c.Database.StatementTimeout.Milliseconds(),
+		c.Database.LockTimeout.Milliseconds(),
 	)
 }
 
+// GetReplicaDSN returns the connection string for a replica in
+// ReplicaHosts, with the same settings as the primary.
+func (c *Config) GetReplicaDSN(host string) string {
+	replica := *c
+	replica.Database.Host = host
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		replica.Database.Host = h
+		replica.Database.Port = port
+	}
+	return replica.GetDSN()
+}
+
 func (c *Config) GetServerAddress() string {
 	return fmt.Sprintf("%s:%s", c.Server.Host, c.Server.Port	)
 }
@@ -66,3 +464,178 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList splits a comma-separated variable, dropping empty entries.
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration such as 30s, got %q", key, value)
+	}
+	return duration, nil
+}
+
+// getEnvNonNegativeDuration is getEnvDuration for settings where 0 means
+// none, such as no delay or no threshold.
+func getEnvNonNegativeDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("%s must be a duration of at least 0s, got %q", key, value)
+	}
+	return duration, nil
+}
+
+func getEnvInt(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer, got %q", key, value)
+	}
+	return number, nil
+}
+
+func getEnvLogLevel(key string, defaultValue slog.Level) (slog.Level, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return 0, fmt.Errorf("%s must be debug, info, warn or error, got %q", key, value)
+	}
+	return level, nil
+}
+
+// getEnvClientSecrets parses a comma-separated list of client=secret pairs.
+func getEnvClientSecrets(key string) (map[string]string, error) {
+	secrets := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		client, secret, found := strings.Cut(pair, "=")
+		if !found || client == "" {
+			return nil, fmt.Errorf("%s must be a comma-separated list of client=secret pairs", key)
+		}
+		if len(client) > maxClientIDLength {
+			return nil, fmt.Errorf("%s: client ID %s must be at most %d characters", key, client, maxClientIDLength)
+		}
+		if len(secret) < minSecretLength {
+			return nil, fmt.Errorf("%s: the secret of %s must be at least %d characters", key, client, minSecretLength)
+		}
+		if _, ok := secrets[client]; ok {
+			return nil, fmt.Errorf("%s lists %s twice", key, client)
+		}
+		secrets[client] = secret
+	}
+	return secrets, nil
+}
+
+func getEnvRateLimit(key, defaultValue string) (RateLimit, error) {
+	value := getEnv(key, defaultValue)
+	limit, err := parseRateLimit(value)
+	if err != nil {
+		return RateLimit{}, fmt.Errorf("%s: %w", key, err)
+	}
+	return limit, nil
+}
+
+// getEnvRouteRateLimits parses a comma-separated list of route=limit pairs,
+// e.g. "POST /transactions=20/s,GET /accounts/{account_id}/statement=5/m".
+func getEnvRouteRateLimits(key string) (map[string]RateLimit, error) {
+	limits := map[string]RateLimit{}
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		route, value, found := strings.Cut(pair, "=")
+		method, path, hasPath := strings.Cut(strings.TrimSpace(route), " ")
+		if !found || !hasPath || method == "" || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("%s must be a comma-separated list of \"METHOD /route=limit\" pairs, got %q", key, pair)
+		}
+		method = strings.ToUpper(method)
+		if !httpMethods[method] {
+			return nil, fmt.Errorf("%s: unknown method %s in %q", key, method, pair)
+		}
+		route = method + " " + path
+		if _, ok := limits[route]; ok {
+			return nil, fmt.Errorf("%s lists %s twice", key, route)
+		}
+		limit, err := parseRateLimit(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", key, route, err)
+		}
+		limits[route] = limit
+	}
+	return limits, nil
+}
+
+var httpMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "OPTIONS": true,
+}
+
+// parseRateLimit parses "off" or requests per period, e.g. "100/s", "30/m"
+// or "500/15m".
+func parseRateLimit(value string) (RateLimit, error) {
+	if value == "off" {
+		return RateLimit{}, nil
+	}
+	requests, period, found := strings.Cut(value, "/")
+	count, err := strconv.Atoi(requests)
+	if !found || err != nil || count <= 0 {
+		return RateLimit{}, fmt.Errorf("limit must be off or requests per period such as 100/s, got %q", value)
+	}
+	if period == "s" || period == "m" || period == "h" {
+		period = "1" + period
+	}
+	per, err := time.ParseDuration(period)
+	if err != nil || per <= 0 {
+		return RateLimit{}, fmt.Errorf("limit must be off or requests per period such as 100/s, got %q", value)
+	}
+	return RateLimit{Requests: count, Per: per}, nil
+}
+
+func getEnvFloat(key string, defaultValue float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number, got %q", key, value)
+	}
+	return f, nil
+}
+
+func getEnvBool(key string, defaultValue bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false, got %q", key, value)
+	}
+	return b, nil
+}
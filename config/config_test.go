@@ -0,0 +1,149 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    RateLimit
+		wantErr bool
+	}{
+		{value: "100/s", want: RateLimit{Requests: 100, Per: time.Second}},
+		{value: "30/m", want: RateLimit{Requests: 30, Per: time.Minute}},
+		{value: "1000/h", want: RateLimit{Requests: 1000, Per: time.Hour}},
+		{value: "500/15m", want: RateLimit{Requests: 500, Per: 15 * time.Minute}},
+		{value: "off", want: RateLimit{}},
+		{value: "10/", wantErr: true},
+		{value: "0/1s", wantErr: true},
+		{value: "-5/1s", wantErr: true},
+		{value: "x/1m", wantErr: true},
+		{value: "10/0s", wantErr: true},
+		{value: "10/-1m", wantErr: true},
+		{value: "10/week", wantErr: true},
+		{value: "10", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseRateLimit(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRateLimit(%q) error = %v, want error: %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseRateLimit(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetEnvRouteRateLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]RateLimit
+		wantErr bool
+	}{
+		{name: "unset", want: map[string]RateLimit{}},
+		{name: "routes", value: "POST /transactions=20/s, GET /accounts/{account_id}/statement=5/m",
+			want: map[string]RateLimit{
+				"POST /transactions":                   {Requests: 20, Per: time.Second},
+				"GET /accounts/{account_id}/statement": {Requests: 5, Per: time.Minute},
+			}},
+		{name: "method is upper-cased", value: "post /transactions=off",
+			want: map[string]RateLimit{"POST /transactions": {}}},
+		{name: "empty entries are skipped", value: ",POST /transactions=1/s,",
+			want: map[string]RateLimit{"POST /transactions": {Requests: 1, Per: time.Second}}},
+		{name: "no limit", value: "POST /transactions", wantErr: true},
+		{name: "no method", value: "/transactions=1/s", wantErr: true},
+		{name: "unknown method", value: "FETCH /transactions=1/s", wantErr: true},
+		{name: "relative path", value: "POST transactions=1/s", wantErr: true},
+		{name: "invalid limit", value: "POST /transactions=x/1m", wantErr: true},
+		{name: "duplicate route", value: "POST /transactions=1/s,post /transactions=2/s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("RATE_LIMIT_ROUTES", tt.value)
+			got, err := getEnvRouteRateLimits("RATE_LIMIT_ROUTES")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getEnvRouteRateLimits(%q) error = %v, want error: %v", tt.value, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getEnvRouteRateLimits(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_RateLimits(t *testing.T) {
+	t.Setenv("RATE_LIMIT_DEFAULT", "50/s")
+	t.Setenv("RATE_LIMIT_ROUTES", "POST /transactions=5/s")
+	t.Setenv("RATE_LIMIT_PER_IP", "off")
+	t.Setenv("RATE_LIMIT_ACCOUNT_DEBITS", "3/m")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	want := RateLimitConfig{
+		Default:       RateLimit{Requests: 50, Per: time.Second},
+		PerIP:         RateLimit{},
+		Routes:        map[string]RateLimit{"POST /transactions": {Requests: 5, Per: time.Second}},
+		AccountDebits: RateLimit{Requests: 3, Per: time.Minute},
+	}
+	if !reflect.DeepEqual(cfg.RateLimits, want) {
+		t.Errorf("RateLimits = %+v, want %+v", cfg.RateLimits, want)
+	}
+
+	t.Setenv("RATE_LIMIT_ACCOUNT_DEBITS", "10/")
+	if _, err := LoadConfig(); err == nil {
+		t.Error("LoadConfig() with RATE_LIMIT_ACCOUNT_DEBITS=10/ succeeded, want an error")
+	}
+}
+
+func TestGetEnvNonNegativeDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "", want: 5 * time.Second},
+		{value: "0s", want: 0},
+		{value: "0", want: 0},
+		{value: "15s", want: 15 * time.Second},
+		{value: "-1s", wantErr: true},
+		{value: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("SERVER_SHUTDOWN_DELAY", tt.value)
+			got, err := getEnvNonNegativeDuration("SERVER_SHUTDOWN_DELAY", 5*time.Second)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getEnvNonNegativeDuration(%q) error = %v, want error: %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("getEnvNonNegativeDuration(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_ZeroDurations(t *testing.T) {
+	t.Setenv("SERVER_SHUTDOWN_DELAY", "0s")
+	t.Setenv("DB_SLOW_QUERY_THRESHOLD", "0")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.Server.ShutdownDelay != 0 || cfg.Database.SlowQueryThreshold != 0 {
+		t.Errorf("ShutdownDelay = %v, SlowQueryThreshold = %v, want 0", cfg.Server.ShutdownDelay, cfg.Database.SlowQueryThreshold)
+	}
+
+	// Timeouts still have to be positive.
+	t.Setenv("REQUEST_TIMEOUT", "0s")
+	if _, err := LoadConfig(); err == nil {
+		t.Error("LoadConfig() with REQUEST_TIMEOUT=0s succeeded, want an error")
+	}
+}
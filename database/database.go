@@ -1,71 +1,154 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	_ "github.com/lib/pq"
 	"triplea-backend-assignment/config"
+	"triplea-backend-assignment/logging"
 )
 
-var DB *sql.DB
+// maxConnectBackoff caps the wait between startup connection attempts.
+const maxConnectBackoff = 30 * time.Second
 
-func Connect(cfg *config.Config) error {
+// Store owns the connection pool. It is created once in main.go and handed
+// to the repositories, so several stores (e.g. against separate schemas in
+// tests) can live in one process.
+type Store struct {
+	db          *sql.DB
+	replicas    []*replica
+	nextReplica atomic.Uint64
+
+	// connectRetries counts the failed attempts Open made before the
+	// database answered.
+	connectRetries int64
+
+	statsMu            sync.Mutex
+	queryStats         map[string]*queryStats
+	slowQueryThreshold time.Duration
+}
+
+// Open connects to the database, retrying with backoff while it is not yet
+// reachable, e.g. when Postgres starts alongside the application.
+func Open(ctx context.Context, cfg *config.Config) (*Store, error) {
 	dsn := cfg.GetDSN()
-	var err error
-	DB, err = sql.Open("postgres", dsn)
+	db, err := sql.Open("postgres", dsn)
 	if err != nil {
-		return fmt.Errorf("failed to open database connection: %w", err)
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
 
-	if err = DB.Ping(); err != nil {
-		return fmt.Errorf("failed to ping database: %w", err)
+	configurePool(db, cfg)
+
+	var pings int64
+	err = retry(ctx, cfg.Database.ConnectAttempts, cfg.Database.ConnectBackoff, func(ctx context.Context) error {
+		pings++
+		return db.PingContext(ctx)
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	DB.SetMaxOpenConns(25)
-	DB.SetMaxIdleConns(5)
+	store := NewStore(db)
+	store.connectRetries = pings - 1
+	store.SetSlowQueryThreshold(cfg.Database.SlowQueryThreshold)
+	// Replicas are not pinged here: an unreachable replica only means reads
+	// stay on the primary until MonitorReplicas finds it healthy.
+	for _, host := range cfg.Database.ReplicaHosts {
+		replicaDB, err := sql.Open("postgres", cfg.GetReplicaDSN(host))
+		if err != nil {
+			store.Close()
+			return nil, fmt.Errorf("failed to open replica %s: %w", host, err)
+		}
+		configurePool(replicaDB, cfg)
+		store.replicas = append(store.replicas, &replica{name: host, db: replicaDB})
+	}
 
-	return nil
+	return store, nil
 }
 
-func Close() error {
-	if DB != nil {
-		return DB.Close()
-	}
-	return nil
+func configurePool(db *sql.DB, cfg *config.Config) {
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.Database.ConnMaxIdleTime)
 }
 
-func Migrate() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS accounts (
-			account_id BIGINT PRIMARY KEY,
-			balance DECIMAL(20, 10) NOT NULL DEFAULT 0,
-			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE TABLE IF NOT EXISTS transactions (
-			id BIGSERIAL PRIMARY KEY,
-			source_account_id BIGINT NOT NULL,
-			destination_account_id BIGINT NOT NULL,
-			amount DECIMAL(20, 10) NOT NULL,
-			status VARCHAR(20) NOT NULL DEFAULT 'pending',
-			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (source_account_id) REFERENCES accounts(account_id),
-			FOREIGN KEY (destination_account_id) REFERENCES accounts(account_id)
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_transactions_source_account ON transactions(source_account_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_transactions_destination_account ON transactions(destination_account_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_transactions_status ON transactions(status)`,
-		`CREATE INDEX IF NOT EXISTS idx_transactions_created_at ON transactions(created_at)`,
-	}
+// retry calls fn up to attempts times, doubling the wait after each failure
+// up to maxConnectBackoff. It returns the last error.
+func retry(ctx context.Context, attempts int, backoff time.Duration, fn func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if attempt >= attempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		logging.FromContext(ctx).WarnContext(ctx, "database not ready, retrying",
+			"attempt", attempt, "attempts", attempts, "error", err.Error(), "backoff", backoff.String())
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		case <-timer.C:
+		}
 
-	for _, query := range queries {
-		if _, err := DB.Exec(query); err != nil {
-			return fmt.Errorf("failed to execute migration: %w", err)
+		backoff *= 2
+		if backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
 		}
 	}
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// DB returns the primary pool, used for writes and for reads that must see
+// them.
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+// Pools returns every connection pool by name: "primary" and the replica
+// hosts.
+func (s *Store) Pools() map[string]*sql.DB {
+	pools := map[string]*sql.DB{"primary": s.db}
+	for _, r := range s.replicas {
+		pools[r.name] = r.db
+	}
+	return pools
+}
 
+// ConnectRetries returns how many times Open retried before the database
+// answered.
+func (s *Store) ConnectRetries() int64 {
+	return s.connectRetries
+}
+
+// Stats reports the statistics of the primary pool.
+func (s *Store) Stats() sql.DBStats {
+	return s.db.Stats()
+}
+
+func (s *Store) Ping(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
 	return nil
 }
 
+func (s *Store) Close() error {
+	for _, r := range s.replicas {
+		r.db.Close()
+	}
+	return s.db.Close()
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	errNotReady := errors.New("connection refused")
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		attempts     int
+		failures     int
+		wantCalls    int
+		wantErr      error
+		wantNilError bool
+	}{
+		{name: "first attempt succeeds", ctx: context.Background(), attempts: 3, failures: 0, wantCalls: 1, wantNilError: true},
+		{name: "succeeds after retries", ctx: context.Background(), attempts: 3, failures: 2, wantCalls: 3, wantNilError: true},
+		{name: "gives up", ctx: context.Background(), attempts: 3, failures: 5, wantCalls: 3, wantErr: errNotReady},
+		{name: "context canceled while waiting", ctx: canceled, attempts: 3, failures: 5, wantCalls: 1, wantErr: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := retry(tt.ctx, tt.attempts, time.Millisecond, func(ctx context.Context) error {
+				calls++
+				if calls <= tt.failures {
+					return errNotReady
+				}
+				return nil
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantNilError {
+				if err != nil {
+					t.Errorf("retry() error = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("retry() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"triplea-backend-assignment/models"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations() error = %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("migration %d has version %d, want versions numbered from 1 without gaps", i, migration.Version)
+		}
+	}
+
+	latest, err := LatestVersion()
+	if err != nil || latest != migrations[len(migrations)-1].Version {
+		t.Errorf("LatestVersion() = %d, %v, want %d", latest, err, migrations[len(migrations)-1].Version)
+	}
+}
+
+func TestTransactionStatusEnumMatchesModels(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations() error = %v", err)
+	}
+	enum := regexp.MustCompile(`CREATE TYPE transaction_status AS ENUM \(([^)]*)\)`)
+	var values string
+	for _, migration := range migrations {
+		if match := enum.FindStringSubmatch(migration.Up); match != nil {
+			values = match[1]
+		}
+	}
+	if values == "" {
+		t.Fatal("no migration creates the transaction_status enum")
+	}
+
+	want := "'" + strings.Join(models.TransactionStatuses, "', '") + "'"
+	if values != want {
+		t.Errorf("transaction_status enum = %s, want %s", values, want)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	file := func(content string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(content)}
+	}
+
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		want    []int
+		wantErr string
+	}{
+		{
+			name: "ordered by version",
+			files: fstest.MapFS{
+				"m/0010_add_index.up.sql":      file("CREATE INDEX"),
+				"m/0010_add_index.down.sql":    file("DROP INDEX"),
+				"m/0002_create_table.up.sql":   file("CREATE TABLE"),
+				"m/0002_create_table.down.sql": file("DROP TABLE"),
+			},
+			want: []int{2, 10},
+		},
+		{
+			name: "missing down file",
+			files: fstest.MapFS{
+				"m/0001_create_table.up.sql": file("CREATE TABLE"),
+			},
+			wantErr: "needs both an up and a down file",
+		},
+		{
+			name: "duplicate version",
+			files: fstest.MapFS{
+				"m/0001_create_table.up.sql":   file("CREATE TABLE"),
+				"m/0001_create_table.down.sql": file("DROP TABLE"),
+				"m/0001_add_index.up.sql":      file("CREATE INDEX"),
+			},
+			wantErr: "is used by",
+		},
+		{
+			name: "invalid file name",
+			files: fstest.MapFS{
+				"m/create_table.sql": file("CREATE TABLE"),
+			},
+			wantErr: "invalid migration file name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := loadMigrations(tt.files, "m")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadMigrations() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadMigrations() error = %v", err)
+			}
+			if len(migrations) != len(tt.want) {
+				t.Fatalf("got %d migrations, want %d", len(migrations), len(tt.want))
+			}
+			for i, version := range tt.want {
+				if migrations[i].Version != version || migrations[i].Up == "" || migrations[i].Down == "" {
+					t.Errorf("migrations[%d] = %+v, want version %d with up and down", i, migrations[i], version)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+-- IF NOT EXISTS lets databases created before versioned migrations adopt
+-- this history without changes.
+CREATE TABLE IF NOT EXISTS accounts (
+    account_id BIGINT PRIMARY KEY,
+    balance DECIMAL(20, 10) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id BIGSERIAL PRIMARY KEY,
+    source_account_id BIGINT NOT NULL,
+    destination_account_id BIGINT NOT NULL,
+    amount DECIMAL(20, 10) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (source_account_id) REFERENCES accounts(account_id),
+    FOREIGN KEY (destination_account_id) REFERENCES accounts(account_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_source_account ON transactions(source_account_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_destination_account ON transactions(destination_account_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_status ON transactions(status);
+CREATE INDEX IF NOT EXISTS idx_transactions_created_at ON transactions(created_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS account_balance_snapshots;
+DROP TABLE IF EXISTS business_days;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS business_days (
+    business_date DATE PRIMARY KEY,
+    high_water_mark BIGINT NOT NULL DEFAULT 0,
+    account_count BIGINT NOT NULL DEFAULT 0,
+    total_balance DECIMAL(30, 10) NOT NULL DEFAULT 0,
+    closed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS account_balance_snapshots (
+    business_date DATE NOT NULL,
+    account_id BIGINT NOT NULL,
+    closing_balance DECIMAL(20, 10) NOT NULL,
+    PRIMARY KEY (business_date, account_id),
+    FOREIGN KEY (business_date) REFERENCES business_days(business_date),
+    FOREIGN KEY (account_id) REFERENCES accounts(account_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_balance_snapshots_account ON account_balance_snapshots(account_id, business_date);
@@ -0,0 +1,12 @@
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS initial_balance DECIMAL(20, 10);
+
+-- Existing accounts get the balance their completed transactions do not
+-- explain.
+UPDATE accounts a SET initial_balance = a.balance
+    - COALESCE((SELECT SUM(amount) FROM transactions
+                WHERE destination_account_id = a.account_id AND status = 'completed'), 0)
+    + COALESCE((SELECT SUM(amount) FROM transactions
+                WHERE source_account_id = a.account_id AND status = 'completed'), 0)
+WHERE a.initial_balance IS NULL;
+
+ALTER TABLE accounts ALTER COLUMN initial_balance SET NOT NULL;
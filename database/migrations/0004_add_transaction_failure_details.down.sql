@@ -0,0 +1,2 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS balance_at_decision;
+ALTER TABLE transactions DROP COLUMN IF EXISTS failure_reason;
@@ -0,0 +1,2 @@
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS failure_reason VARCHAR(64);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS balance_at_decision DECIMAL(20, 10);
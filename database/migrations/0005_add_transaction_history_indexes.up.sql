@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_transactions_source_account_id_desc ON transactions(source_account_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_transactions_destination_account_id_desc ON transactions(destination_account_id, id DESC);
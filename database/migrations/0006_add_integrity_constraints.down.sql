@@ -0,0 +1,14 @@
+ALTER TABLE accounts
+    DROP CONSTRAINT IF EXISTS accounts_balance_within_overdraft,
+    DROP COLUMN IF EXISTS overdraft_limit;
+
+ALTER TABLE transactions
+    DROP CONSTRAINT IF EXISTS transactions_distinct_accounts,
+    DROP CONSTRAINT IF EXISTS transactions_amount_positive;
+
+ALTER TABLE transactions
+    ALTER COLUMN status DROP DEFAULT,
+    ALTER COLUMN status TYPE VARCHAR(20) USING status::text,
+    ALTER COLUMN status SET DEFAULT 'pending';
+
+DROP TYPE IF EXISTS transaction_status;
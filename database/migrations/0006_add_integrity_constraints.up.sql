@@ -0,0 +1,20 @@
+-- Backstops for the validation done in Go. Existing rows must already satisfy
+-- them or the migration fails and nothing is changed.
+
+CREATE TYPE transaction_status AS ENUM ('pending', 'completed', 'failed');
+
+ALTER TABLE transactions
+    ALTER COLUMN status DROP DEFAULT,
+    ALTER COLUMN status TYPE transaction_status USING status::transaction_status,
+    ALTER COLUMN status SET DEFAULT 'pending';
+
+ALTER TABLE transactions
+    ADD CONSTRAINT transactions_amount_positive CHECK (amount > 0),
+    ADD CONSTRAINT transactions_distinct_accounts CHECK (source_account_id <> destination_account_id);
+
+ALTER TABLE accounts
+    ADD COLUMN overdraft_limit DECIMAL(20, 10) NOT NULL DEFAULT 0
+        CONSTRAINT accounts_overdraft_limit_non_negative CHECK (overdraft_limit >= 0);
+
+ALTER TABLE accounts
+    ADD CONSTRAINT accounts_balance_within_overdraft CHECK (balance >= -overdraft_limit);
@@ -0,0 +1,13 @@
+ALTER TABLE schema_migrations
+    ALTER COLUMN applied_at TYPE TIMESTAMP USING applied_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE business_days
+    ALTER COLUMN closed_at TYPE TIMESTAMP USING closed_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE transactions
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE accounts
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE current_setting('TimeZone');
@@ -0,0 +1,17 @@
+-- Existing values were written as local time of the server's TimeZone
+-- setting, which is also the default for this session, so AT TIME ZONE
+-- reads them the way they were written.
+
+ALTER TABLE accounts
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE transactions
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE business_days
+    ALTER COLUMN closed_at TYPE TIMESTAMPTZ USING closed_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE schema_migrations
+    ALTER COLUMN applied_at TYPE TIMESTAMPTZ USING applied_at AT TIME ZONE current_setting('TimeZone');
@@ -0,0 +1,44 @@
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM transaction_archives) THEN
+        RAISE EXCEPTION 'cannot undo partitioning: archived transactions are no longer in the database';
+    END IF;
+END
+$$;
+
+DROP TABLE archived_account_balances;
+DROP TABLE transaction_archives;
+
+CREATE TABLE transactions_unpartitioned (
+    id BIGINT NOT NULL DEFAULT nextval('transactions_id_seq') PRIMARY KEY,
+    source_account_id BIGINT NOT NULL,
+    destination_account_id BIGINT NOT NULL,
+    amount DECIMAL(20, 10) NOT NULL,
+    status transaction_status NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    failure_reason VARCHAR(64),
+    balance_at_decision DECIMAL(20, 10),
+    CONSTRAINT transactions_source_account_id_fkey FOREIGN KEY (source_account_id) REFERENCES accounts(account_id),
+    CONSTRAINT transactions_destination_account_id_fkey FOREIGN KEY (destination_account_id) REFERENCES accounts(account_id),
+    CONSTRAINT transactions_amount_positive CHECK (amount > 0),
+    CONSTRAINT transactions_distinct_accounts CHECK (source_account_id <> destination_account_id)
+);
+
+INSERT INTO transactions_unpartitioned (id, source_account_id, destination_account_id, amount, status,
+                                        created_at, updated_at, failure_reason, balance_at_decision)
+SELECT id, source_account_id, destination_account_id, amount, status,
+       created_at, updated_at, failure_reason, balance_at_decision
+FROM transactions;
+
+ALTER SEQUENCE transactions_id_seq OWNED BY transactions_unpartitioned.id;
+DROP TABLE transactions;
+ALTER TABLE transactions_unpartitioned RENAME TO transactions;
+ALTER INDEX transactions_unpartitioned_pkey RENAME TO transactions_pkey;
+
+CREATE INDEX idx_transactions_source_account ON transactions(source_account_id);
+CREATE INDEX idx_transactions_destination_account ON transactions(destination_account_id);
+CREATE INDEX idx_transactions_status ON transactions(status);
+CREATE INDEX idx_transactions_created_at ON transactions(created_at);
+CREATE INDEX idx_transactions_source_account_id_desc ON transactions(source_account_id, id DESC);
+CREATE INDEX idx_transactions_destination_account_id_desc ON transactions(destination_account_id, id DESC);
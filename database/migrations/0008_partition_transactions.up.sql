@@ -0,0 +1,78 @@
+-- Transactions are range partitioned by the UTC month of created_at, in
+-- partitions named transactions_pYYYYMM. The partition maintenance job
+-- creates upcoming months; rows outside every partition land in
+-- transactions_default until their month's partition is created. A
+-- partitioned table's primary key must include the partition key.
+
+CREATE TABLE transactions_partitioned (
+    id BIGINT NOT NULL DEFAULT nextval('transactions_id_seq'),
+    source_account_id BIGINT NOT NULL,
+    destination_account_id BIGINT NOT NULL,
+    amount DECIMAL(20, 10) NOT NULL,
+    status transaction_status NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    failure_reason VARCHAR(64),
+    balance_at_decision DECIMAL(20, 10),
+    CONSTRAINT transactions_source_account_id_fkey FOREIGN KEY (source_account_id) REFERENCES accounts(account_id),
+    CONSTRAINT transactions_destination_account_id_fkey FOREIGN KEY (destination_account_id) REFERENCES accounts(account_id),
+    CONSTRAINT transactions_amount_positive CHECK (amount > 0),
+    CONSTRAINT transactions_distinct_accounts CHECK (source_account_id <> destination_account_id)
+) PARTITION BY RANGE (created_at);
+
+DO $$
+DECLARE
+    partition_start TIMESTAMP;
+BEGIN
+    FOR partition_start IN
+        SELECT generate_series(
+            date_trunc('month', COALESCE((SELECT MIN(created_at) FROM transactions), CURRENT_TIMESTAMP) AT TIME ZONE 'UTC'),
+            date_trunc('month', CURRENT_TIMESTAMP AT TIME ZONE 'UTC') + INTERVAL '3 months',
+            INTERVAL '1 month')
+    LOOP
+        EXECUTE format('CREATE TABLE %I PARTITION OF transactions_partitioned FOR VALUES FROM (%L) TO (%L)',
+            'transactions_p' || to_char(partition_start, 'YYYYMM'),
+            partition_start AT TIME ZONE 'UTC',
+            (partition_start + INTERVAL '1 month') AT TIME ZONE 'UTC');
+    END LOOP;
+END
+$$;
+
+CREATE TABLE transactions_default PARTITION OF transactions_partitioned DEFAULT;
+
+INSERT INTO transactions_partitioned (id, source_account_id, destination_account_id, amount, status,
+                                      created_at, updated_at, failure_reason, balance_at_decision)
+SELECT id, source_account_id, destination_account_id, amount, status,
+       created_at, updated_at, failure_reason, balance_at_decision
+FROM transactions;
+
+ALTER SEQUENCE transactions_id_seq OWNED BY transactions_partitioned.id;
+DROP TABLE transactions;
+ALTER TABLE transactions_partitioned RENAME TO transactions;
+
+ALTER TABLE transactions ADD CONSTRAINT transactions_pkey PRIMARY KEY (id, created_at);
+CREATE INDEX idx_transactions_source_account ON transactions(source_account_id);
+CREATE INDEX idx_transactions_destination_account ON transactions(destination_account_id);
+CREATE INDEX idx_transactions_status ON transactions(status);
+CREATE INDEX idx_transactions_created_at ON transactions(created_at);
+CREATE INDEX idx_transactions_source_account_id_desc ON transactions(source_account_id, id DESC);
+CREATE INDEX idx_transactions_destination_account_id_desc ON transactions(destination_account_id, id DESC);
+
+-- One row per partition exported and dropped by the archive command.
+-- Transactions created before the latest range_end are no longer in the
+-- database.
+CREATE TABLE transaction_archives (
+    partition_name VARCHAR(63) PRIMARY KEY,
+    range_start TIMESTAMPTZ NOT NULL,
+    range_end TIMESTAMPTZ NOT NULL,
+    row_count BIGINT NOT NULL,
+    file_name TEXT NOT NULL,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The net effect of each account's archived completed transactions, so
+-- that ledger checks still explain current balances.
+CREATE TABLE archived_account_balances (
+    account_id BIGINT PRIMARY KEY REFERENCES accounts(account_id),
+    net_amount DECIMAL(30, 10) NOT NULL
+);
@@ -0,0 +1,10 @@
+-- Nonces of signed requests, kept until their timestamp is too old to be
+-- accepted anyway, so a captured request cannot be replayed.
+CREATE TABLE IF NOT EXISTS request_nonces (
+    client_id VARCHAR(64) NOT NULL,
+    nonce VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (client_id, nonce)
+);
+
+CREATE INDEX IF NOT EXISTS idx_request_nonces_expires_at ON request_nonces(expires_at);
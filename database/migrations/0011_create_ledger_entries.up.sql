@@ -0,0 +1,20 @@
+-- Double-entry legs of every completed transfer: a debit (negative amount)
+-- on the source account and a credit on the destination, written in the
+-- same transaction that completes the transfer. The trial balance checks
+-- each completed transfer against its legs. Legs of archived partitions
+-- are deleted with them; archived_account_balances keeps their net effect.
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT NOT NULL,
+    account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    amount DECIMAL(20, 10) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_transaction_id ON ledger_entries(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account_id ON ledger_entries(account_id);
+
+INSERT INTO ledger_entries (transaction_id, account_id, amount, created_at)
+SELECT id, source_account_id, -amount, updated_at FROM transactions WHERE status = 'completed'
+UNION ALL
+SELECT id, destination_account_id, amount, updated_at FROM transactions WHERE status = 'completed';
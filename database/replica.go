@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"triplea-backend-assignment/logging"
+)
+
+type consistencyKey struct{}
+
+// WithStrongConsistency marks ctx so that reads go to the primary even when
+// replicas are configured, e.g. to read back a write straight away.
+func WithStrongConsistency(ctx context.Context) context.Context {
+	return context.WithValue(ctx, consistencyKey{}, true)
+}
+
+func StrongConsistency(ctx context.Context) bool {
+	strong, _ := ctx.Value(consistencyKey{}).(bool)
+	return strong
+}
+
+type replica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// ReadDB returns the pool for a read-only query that may see slightly stale
+// data: a healthy replica, chosen round-robin, or the primary when there is
+// none or ctx asks for strong consistency.
+func (s *Store) ReadDB(ctx context.Context) *sql.DB {
+	if len(s.replicas) == 0 || StrongConsistency(ctx) {
+		return s.db
+	}
+	start := s.nextReplica.Add(1)
+	for i := range s.replicas {
+		r := s.replicas[(start+uint64(i))%uint64(len(s.replicas))]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+	return s.db
+}
+
+// MonitorReplicas checks every replica once per interval until ctx is done.
+// A replica serves reads only while it answers and lags the primary by at
+// most maxLag; replicas start out unhealthy until their first check.
+func (s *Store) MonitorReplicas(ctx context.Context, interval, maxLag time.Duration) {
+	if len(s.replicas) == 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, r := range s.replicas {
+			s.checkReplica(ctx, r, maxLag)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Store) checkReplica(ctx context.Context, r *replica, maxLag time.Duration) {
+	checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	lag, err := s.replicationLag(checkCtx, r.db)
+	healthy := err == nil && lag <= maxLag
+	if r.healthy.Swap(healthy) == healthy {
+		return
+	}
+	switch {
+	case err != nil:
+		logging.FromContext(ctx).WarnContext(ctx, "replica is unhealthy, reading from primary",
+			"replica", r.name, "error", err.Error())
+	case !healthy:
+		logging.FromContext(ctx).WarnContext(ctx, "replica lags, reading from primary",
+			"replica", r.name, "lag", lag.String(), "max_lag", maxLag.String())
+	default:
+		logging.FromContext(ctx).InfoContext(ctx, "replica is healthy", "replica", r.name, "lag", lag.String())
+	}
+}
+
+// replicaStatus is what a standby reports about itself. caughtUp is whether
+// it has replayed the primary's WAL up to the position read just before;
+// replayAge is the age of the last transaction it replayed, NULL if it has
+// not replayed any since it started.
+type replicaStatus struct {
+	inRecovery bool
+	streaming  bool
+	caughtUp   bool
+	replayAge  sql.NullFloat64
+}
+
+// lag reports how far the standby's data is behind the primary. A server
+// that is not a standby, or whose WAL receiver is not streaming, has no
+// meaningful lag and is an error: it may be serving data that will never
+// catch up.
+func (st replicaStatus) lag() (time.Duration, error) {
+	switch {
+	case !st.inRecovery:
+		return 0, fmt.Errorf("replica is not in recovery")
+	case !st.streaming:
+		return 0, fmt.Errorf("replica is not streaming from the primary")
+	case st.caughtUp:
+		return 0, nil
+	case !st.replayAge.Valid:
+		return 0, fmt.Errorf("replica is behind the primary and has not replayed any transaction")
+	}
+	return time.Duration(st.replayAge.Float64 * float64(time.Second)), nil
+}
+
+// replicationLag compares a standby with the primary's current WAL position.
+// A standby that has replayed up to it is current however long ago the last
+// write was; one that has not lags by the age of its last replayed
+// transaction.
+func (s *Store) replicationLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	var primaryLSN string
+	if err := s.db.QueryRowContext(ctx, `SELECT pg_current_wal_lsn()::text`).Scan(&primaryLSN); err != nil {
+		return 0, fmt.Errorf("failed to read primary WAL position: %w", err)
+	}
+
+	query := `SELECT pg_is_in_recovery(),
+				EXISTS (SELECT 1 FROM pg_stat_wal_receiver WHERE status = 'streaming'),
+				COALESCE(pg_last_wal_replay_lsn() >= $1::pg_lsn, false),
+				EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())`
+	var st replicaStatus
+	if err := db.QueryRowContext(ctx, query, primaryLSN).Scan(&st.inRecovery, &st.streaming, &st.caughtUp, &st.replayAge); err != nil {
+		return 0, fmt.Errorf("failed to read replica status: %w", err)
+	}
+	return st.lag()
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestReadDB(t *testing.T) {
+	// sql.Open does not connect, so the pools only serve as identities.
+	open := func() *sql.DB {
+		db, err := sql.Open("postgres", "host=localhost")
+		if err != nil {
+			t.Fatalf("sql.Open() error = %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+	primary := open()
+	first, second := &replica{name: "first", db: open()}, &replica{name: "second", db: open()}
+	store := NewStore(primary)
+	store.replicas = []*replica{first, second}
+
+	ctx := context.Background()
+	if got := store.ReadDB(ctx); got != primary {
+		t.Error("ReadDB() with no healthy replica did not return the primary")
+	}
+
+	first.healthy.Store(true)
+	second.healthy.Store(true)
+	seen := map[*sql.DB]bool{}
+	for i := 0; i < 4; i++ {
+		seen[store.ReadDB(ctx)] = true
+	}
+	if !seen[first.db] || !seen[second.db] || seen[primary] {
+		t.Error("ReadDB() did not spread reads over the healthy replicas")
+	}
+
+	if got := store.ReadDB(WithStrongConsistency(ctx)); got != primary {
+		t.Error("ReadDB() with strong consistency did not return the primary")
+	}
+
+	first.healthy.Store(false)
+	for i := 0; i < 4; i++ {
+		if got := store.ReadDB(ctx); got != second.db {
+			t.Fatal("ReadDB() returned an unhealthy replica")
+		}
+	}
+
+	if got := NewStore(primary).ReadDB(ctx); got != primary {
+		t.Error("ReadDB() without replicas did not return the primary")
+	}
+}
+
+func TestReplicaStatusLag(t *testing.T) {
+	age := func(seconds float64) sql.NullFloat64 { return sql.NullFloat64{Float64: seconds, Valid: true} }
+	tests := []struct {
+		name    string
+		status  replicaStatus
+		want    time.Duration
+		wantErr bool
+	}{
+		{"caught up", replicaStatus{inRecovery: true, streaming: true, caughtUp: true, replayAge: age(3600)}, 0, false},
+		{"behind", replicaStatus{inRecovery: true, streaming: true, replayAge: age(2.5)}, 2500 * time.Millisecond, false},
+		{"behind without replayed transaction", replicaStatus{inRecovery: true, streaming: true}, 0, true},
+		{"receiver disconnected", replicaStatus{inRecovery: true, caughtUp: true, replayAge: age(0)}, 0, true},
+		{"not a standby", replicaStatus{streaming: true, caughtUp: true}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.status.lag()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lag() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("lag() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"triplea-backend-assignment/logging"
+	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/tracing"
+)
+
+// Statement is a named query prepared on the primary when it is created and
+// on a replica the first time it runs there. Every execution is recorded
+// under its name; see Store.QueryStats.
+type Statement struct {
+	store *Store
+	name  string
+	query string
+
+	mu    sync.Mutex
+	stmts map[*sql.DB]*sql.Stmt
+}
+
+// Prepare prepares query on the primary, so a query that does not match the
+// schema fails at startup rather than on first use.
+func (s *Store) Prepare(ctx context.Context, name, query string) (*Statement, error) {
+	stmt, err := s.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare %s: %w", name, err)
+	}
+	return &Statement{
+		store: s,
+		name:  name,
+		query: query,
+		stmts: map[*sql.DB]*sql.Stmt{s.db: stmt},
+	}, nil
+}
+
+func (st *Statement) prepared(ctx context.Context, db *sql.DB) (*sql.Stmt, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if stmt, ok := st.stmts[db]; ok {
+		return stmt, nil
+	}
+	stmt, err := db.PrepareContext(ctx, st.query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare %s: %w", st.name, err)
+	}
+	st.stmts[db] = stmt
+	return stmt, nil
+}
+
+// On binds the statement to a pool, e.g. Store.DB() or Store.ReadDB(ctx).
+func (st *Statement) On(ctx context.Context, db *sql.DB) *BoundStatement {
+	stmt, err := st.prepared(ctx, db)
+	return &BoundStatement{statement: st, stmt: stmt, err: err}
+}
+
+// InTx binds the statement to a transaction on the primary.
+func (st *Statement) InTx(ctx context.Context, tx *sql.Tx) *BoundStatement {
+	return st.InTxOn(ctx, st.store.db, tx)
+}
+
+// InTxOn binds the statement to a transaction begun on db, e.g. a read-only
+// transaction on Store.ReadDB(ctx).
+func (st *Statement) InTxOn(ctx context.Context, db *sql.DB, tx *sql.Tx) *BoundStatement {
+	stmt, err := st.prepared(ctx, db)
+	if err != nil {
+		return &BoundStatement{statement: st, err: err}
+	}
+	return &BoundStatement{statement: st, stmt: tx.StmtContext(ctx, stmt)}
+}
+
+// BoundStatement runs a Statement on a pool or transaction. An error from
+// preparing it is returned by whichever method runs it.
+type BoundStatement struct {
+	statement *Statement
+	stmt      *sql.Stmt
+	err       error
+}
+
+func (b *BoundStatement) QueryRowContext(ctx context.Context, args ...interface{}) *Row {
+	c := b.statement.begin(ctx)
+	if b.err != nil {
+		return &Row{call: c, err: b.err}
+	}
+	return &Row{call: c, row: b.stmt.QueryRowContext(c.ctx, args...)}
+}
+
+func (b *BoundStatement) QueryContext(ctx context.Context, args ...interface{}) (*Rows, error) {
+	c := b.statement.begin(ctx)
+	if b.err != nil {
+		c.finish(0, b.err)
+		return nil, b.err
+	}
+	rows, err := b.stmt.QueryContext(c.ctx, args...)
+	if err != nil {
+		c.finish(0, err)
+		return nil, err
+	}
+	return &Rows{Rows: rows, call: c}, nil
+}
+
+func (b *BoundStatement) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
+	c := b.statement.begin(ctx)
+	if b.err != nil {
+		c.finish(0, b.err)
+		return nil, b.err
+	}
+	result, err := b.stmt.ExecContext(c.ctx, args...)
+	var affected int64
+	if err == nil {
+		affected, _ = result.RowsAffected()
+	}
+	c.finish(affected, err)
+	return result, err
+}
+
+// call is one execution of a statement. It is traced as a client span and
+// recorded in the query stats when it finishes.
+type call struct {
+	ctx       context.Context
+	statement *Statement
+	start     time.Time
+	span      trace.Span
+}
+
+func (st *Statement) begin(ctx context.Context) *call {
+	ctx, span := tracing.Tracer().Start(ctx, st.name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBStatement(st.query),
+		))
+	return &call{ctx: ctx, statement: st, start: time.Now(), span: span}
+}
+
+func (c *call) finish(rows int64, err error) {
+	c.statement.store.observe(c.ctx, c.statement.name, time.Since(c.start), rows, err)
+	c.span.SetAttributes(attribute.Int64("db.rows", rows))
+	tracing.End(c.span, err)
+}
+
+// Row is recorded when it is scanned.
+type Row struct {
+	call *call
+	row  *sql.Row
+	err  error
+}
+
+func (r *Row) Scan(dest ...interface{}) error {
+	err := r.err
+	if err == nil {
+		err = r.row.Scan(dest...)
+	}
+	var rows int64
+	if err == nil {
+		rows = 1
+	}
+	observed := err
+	if errors.Is(err, sql.ErrNoRows) {
+		observed = nil
+	}
+	r.call.finish(rows, observed)
+	return err
+}
+
+// Rows counts the rows read and is recorded when it is closed, so its
+// duration includes the time the caller spends consuming the rows.
+type Rows struct {
+	*sql.Rows
+	call   *call
+	count  int64
+	closed bool
+}
+
+func (r *Rows) Next() bool {
+	if r.Rows.Next() {
+		r.count++
+		return true
+	}
+	return false
+}
+
+func (r *Rows) Close() error {
+	err := r.Rows.Close()
+	if !r.closed {
+		r.closed = true
+		observed := err
+		if observed == nil {
+			observed = r.Rows.Err()
+		}
+		r.call.finish(r.count, observed)
+	}
+	return err
+}
+
+type queryStats struct {
+	calls    int64
+	errors   int64
+	rows     int64
+	total    time.Duration
+	max      time.Duration
+	slowRuns int64
+}
+
+// SetSlowQueryThreshold makes every query that runs longer than threshold
+// get logged, with the request ID of ctx when it runs for a request. Zero
+// disables the log.
+func (s *Store) SetSlowQueryThreshold(threshold time.Duration) {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	s.slowQueryThreshold = threshold
+}
+
+func (s *Store) observe(ctx context.Context, name string, duration time.Duration, rows int64, err error) {
+	s.statsMu.Lock()
+	if s.queryStats == nil {
+		s.queryStats = map[string]*queryStats{}
+	}
+	stats, ok := s.queryStats[name]
+	if !ok {
+		stats = &queryStats{}
+		s.queryStats[name] = stats
+	}
+	stats.calls++
+	stats.rows += rows
+	stats.total += duration
+	if duration > stats.max {
+		stats.max = duration
+	}
+	if err != nil {
+		stats.errors++
+	}
+	slow := s.slowQueryThreshold > 0 && duration > s.slowQueryThreshold
+	if slow {
+		stats.slowRuns++
+	}
+	s.statsMu.Unlock()
+
+	if slow {
+		attrs := []any{"query", name, "duration_ms", durationMS(duration), "rows", rows}
+		if err != nil {
+			attrs = append(attrs, "error", err.Error())
+		}
+		logging.FromContext(ctx).WarnContext(ctx, "slow query", attrs...)
+	}
+}
+
+// QueryStats reports what every named statement has cost since startup,
+// most total time first.
+func (s *Store) QueryStats() []models.QueryStats {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	result := make([]models.QueryStats, 0, len(s.queryStats))
+	for name, stats := range s.queryStats {
+		result = append(result, models.QueryStats{
+			Name:      name,
+			Calls:     stats.calls,
+			Errors:    stats.errors,
+			Rows:      stats.rows,
+			SlowCalls: stats.slowRuns,
+			TotalMS:   durationMS(stats.total),
+			MeanMS:    durationMS(stats.total / time.Duration(stats.calls)),
+			MaxMS:     durationMS(stats.max),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalMS != result[j].TotalMS {
+			return result[i].TotalMS > result[j].TotalMS
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func durationMS(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
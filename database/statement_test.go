@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"triplea-backend-assignment/models"
+)
+
+func TestQueryStats(t *testing.T) {
+	store := NewStore(nil)
+	store.SetSlowQueryThreshold(100 * time.Millisecond)
+	ctx := context.Background()
+
+	store.observe(ctx, "AccountRepository.GetByID", 2*time.Millisecond, 1, nil)
+	store.observe(ctx, "AccountRepository.GetByID", 4*time.Millisecond, 0, errors.New("connection reset"))
+	store.observe(ctx, "AccountRepository.GetByIDWithLock", 300*time.Millisecond, 1, nil)
+	store.observe(ctx, "AccountRepository.GetByIDWithLock", 100*time.Millisecond, 1, nil)
+
+	want := []models.QueryStats{
+		{Name: "AccountRepository.GetByIDWithLock", Calls: 2, Rows: 2, SlowCalls: 1, TotalMS: 400, MeanMS: 200, MaxMS: 300},
+		{Name: "AccountRepository.GetByID", Calls: 2, Errors: 1, Rows: 1, TotalMS: 6, MeanMS: 3, MaxMS: 4},
+	}
+	got := store.QueryStats()
+	if len(got) != len(want) {
+		t.Fatalf("QueryStats() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("QueryStats()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRowScanRecordsCall(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantErrors int64
+	}{
+		{name: "no rows is not an error", err: sql.ErrNoRows, wantErrors: 0},
+		{name: "failure", err: errors.New("canceling statement due to statement timeout"), wantErrors: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStore(nil)
+			statement := &Statement{store: store, name: "q"}
+			row := &Row{call: statement.begin(context.Background()), err: tt.err}
+
+			if err := row.Scan(); !errors.Is(err, tt.err) {
+				t.Errorf("Scan() error = %v, want %v", err, tt.err)
+			}
+			stats := store.QueryStats()
+			if len(stats) != 1 || stats[0].Calls != 1 || stats[0].Rows != 0 || stats[0].Errors != tt.wantErrors {
+				t.Errorf("QueryStats() = %+v, want one call, no rows and %d errors", stats, tt.wantErrors)
+			}
+		})
+	}
+}
@@ -32,12 +32,12 @@ func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.accountService.CreateAccount(&req); err != nil {
+	if err := h.accountService.CreateAccount(r.Context(), &req); err != nil {
 		if isValidationError(err) || isAccountExistsError(err) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeServerError(w, r, err)
 		return
 	}
 
@@ -58,13 +58,18 @@ func (h *AccountHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	account, err := h.accountService.GetAccount(accountID)
+	if !ownsAccount(r, accountID) {
+		http.Error(w, "Access to this account is not allowed", http.StatusForbidden)
+		return
+	}
+
+	account, err := h.accountService.GetAccount(r.Context(), accountID)
 	if err != nil {
 		if isAccountNotFoundError(err) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeServerError(w, r, err)
 		return
 	}
 
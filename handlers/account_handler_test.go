@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"triplea-backend-assignment/auth"
+	"triplea-backend-assignment/repository"
+	"triplea-backend-assignment/service"
+)
+
+func TestGetAccount_Timestamps(t *testing.T) {
+	store := repository.NewMemoryStore()
+	if err := store.Accounts().Create(context.Background(), 1, "10"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	handler := NewAccountHandler(service.NewAccountService(store.Accounts()))
+
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/accounts/1", nil), map[string]string{"account_id": "1"})
+	req = req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{Kind: auth.KindService}))
+	rec := httptest.NewRecorder()
+	handler.GetAccount(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GetAccount() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	for _, field := range []string{"created_at", "updated_at"} {
+		value, ok := body[field].(string)
+		if !ok {
+			t.Errorf("%s = %v, want a string", field, body[field])
+			continue
+		}
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			t.Errorf("%s = %q is not RFC 3339: %v", field, value, err)
+		}
+		if !strings.HasSuffix(value, "Z") {
+			t.Errorf("%s = %q, want UTC", field, value)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/service"
+)
+
+type BusinessDayHandler struct {
+	businessDayService *service.BusinessDayService
+}
+
+func NewBusinessDayHandler(businessDayService *service.BusinessDayService) *BusinessDayHandler {
+	return &BusinessDayHandler{
+		businessDayService: businessDayService,
+	}
+}
+
+func (h *BusinessDayHandler) CloseBusinessDay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.CloseBusinessDayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	day, err := h.businessDayService.CloseBusinessDay(r.Context(), &req)
+	if err != nil {
+		if isValidationError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if isBusinessDayClosedError(err) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(day)
+}
+
+func (h *BusinessDayHandler) GetSnapshots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	snapshots, err := h.businessDayService.GetSnapshots(r.Context(), vars["business_date"])
+	if err != nil {
+		if isValidationError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if isBusinessDayNotFoundError(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshots)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"triplea-backend-assignment/models"
+)
+
+// DatabaseStatsSource reports connection pool and per-query statistics.
+// *database.Store implements it.
+type DatabaseStatsSource interface {
+	Stats() sql.DBStats
+	QueryStats() []models.QueryStats
+}
+
+type DatabaseHandler struct {
+	pool DatabaseStatsSource
+}
+
+func NewDatabaseHandler(pool DatabaseStatsSource) *DatabaseHandler {
+	return &DatabaseHandler{
+		pool: pool,
+	}
+}
+
+func (h *DatabaseHandler) GetPoolStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.NewPoolStats(h.pool.Stats()))
+}
+
+func (h *DatabaseHandler) GetQueryStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.pool.QueryStats())
+}
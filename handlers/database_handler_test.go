@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"triplea-backend-assignment/models"
+)
+
+type fakePool struct {
+	stats   sql.DBStats
+	queries []models.QueryStats
+}
+
+func (p fakePool) Stats() sql.DBStats {
+	return p.stats
+}
+
+func (p fakePool) QueryStats() []models.QueryStats {
+	return p.queries
+}
+
+func TestGetPoolStats(t *testing.T) {
+	handler := NewDatabaseHandler(fakePool{stats: sql.DBStats{
+		MaxOpenConnections: 25,
+		OpenConnections:    4,
+		InUse:              3,
+		Idle:               1,
+		WaitCount:          7,
+		WaitDuration:       1500 * time.Millisecond,
+	}})
+
+	rec := httptest.NewRecorder()
+	handler.GetPoolStats(rec, httptest.NewRequest(http.MethodGet, "/admin/db/stats", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GetPoolStats() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var got models.PoolStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	want := models.PoolStats{MaxOpenConnections: 25, OpenConnections: 4, InUse: 3, Idle: 1, WaitCount: 7, WaitDurationMS: 1500}
+	if got != want {
+		t.Errorf("GetPoolStats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestGetQueryStats(t *testing.T) {
+	queries := []models.QueryStats{
+		{Name: "AccountRepository.GetByIDWithLock", Calls: 4, Rows: 4, SlowCalls: 1, TotalMS: 900, MeanMS: 225, MaxMS: 600},
+		{Name: "AccountRepository.GetByID", Calls: 10, Rows: 9, TotalMS: 12, MeanMS: 1.2, MaxMS: 3},
+	}
+	handler := NewDatabaseHandler(fakePool{queries: queries})
+
+	rec := httptest.NewRecorder()
+	handler.GetQueryStats(rec, httptest.NewRequest(http.MethodGet, "/admin/db/queries", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GetQueryStats() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var got []models.QueryStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(got) != len(queries) {
+		t.Fatalf("GetQueryStats() = %+v, want %+v", got, queries)
+	}
+	for i := range queries {
+		if got[i] != queries[i] {
+			t.Errorf("GetQueryStats()[%d] = %+v, want %+v", i, got[i], queries[i])
+		}
+	}
+}
@@ -1,9 +1,18 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"strings"
+
+	"triplea-backend-assignment/logging"
 )
 
+// StatusClientClosedRequest is the non-standard status nginx uses for
+// requests the client abandoned before the response was written.
+const StatusClientClosedRequest = 499
+
 func isValidationError(err error) bool {
 	if err == nil {
 		return false
@@ -36,3 +45,68 @@ func isInsufficientBalanceError(err error) bool {
 	return strings.Contains(err.Error(), "insufficient balance")
 }
 
+func isTransactionNotFoundError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return strings.Contains(err.Error(), "transaction not found")
+}
+
+func isBusinessDayClosedError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return strings.Contains(err.Error(), "is already closed")
+}
+
+func isBusinessDayNotFoundError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return strings.Contains(err.Error(), "business day not found")
+}
+
+func isAPIKeyNotFoundError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return strings.Contains(err.Error(), "API key not found")
+}
+
+// The driver does not always return the context's error once a query has been
+// interrupted, so the request context is consulted as well.
+func isDeadlineExceededError(r *http.Request, err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(r.Context().Err(), context.DeadlineExceeded)
+}
+
+// isDatabaseTimeoutError reports a query stopped by the session's
+// statement_timeout or lock_timeout.
+func isDatabaseTimeoutError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return strings.Contains(err.Error(), "canceling statement due to statement timeout") ||
+		strings.Contains(err.Error(), "canceling statement due to lock timeout")
+}
+
+func isCanceledError(r *http.Request, err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(r.Context().Err(), context.Canceled)
+}
+
+// writeServerError reports an error that is not the client's fault. The
+// client only gets a generic message, so the error itself is logged with the
+// request ID the client can quote.
+func writeServerError(w http.ResponseWriter, r *http.Request, err error) {
+	logger := logging.FromContext(r.Context())
+	if isDeadlineExceededError(r, err) || isDatabaseTimeoutError(err) {
+		logger.WarnContext(r.Context(), "request timed out", "error", err.Error())
+		http.Error(w, "Request timed out", http.StatusGatewayTimeout)
+		return
+	}
+	if isCanceledError(r, err) {
+		w.WriteHeader(StatusClientClosedRequest)
+		return
+	}
+	logger.ErrorContext(r.Context(), "internal server error", "error", err.Error())
+	http.Error(w, "Internal server error", http.StatusInternalServerError)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"triplea-backend-assignment/logging"
+)
+
+func TestWriteServerError(t *testing.T) {
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		err        error
+		wantStatus int
+		wantLevel  string
+	}{
+		{
+			name:       "deadline exceeded",
+			ctx:        context.Background(),
+			err:        fmt.Errorf("failed to get account: %w", context.DeadlineExceeded),
+			wantStatus: http.StatusGatewayTimeout,
+			wantLevel:  "WARN",
+		},
+		{
+			name:       "client canceled",
+			ctx:        context.Background(),
+			err:        fmt.Errorf("failed to get account: %w", context.Canceled),
+			wantStatus: StatusClientClosedRequest,
+		},
+		{
+			name:       "driver error after request deadline",
+			ctx:        expired,
+			err:        errors.New("pq: canceling statement due to user request"),
+			wantStatus: http.StatusGatewayTimeout,
+			wantLevel:  "WARN",
+		},
+		{
+			name:       "driver error after client went away",
+			ctx:        canceled,
+			err:        errors.New("pq: canceling statement due to user request"),
+			wantStatus: StatusClientClosedRequest,
+		},
+		{
+			name:       "database statement timeout",
+			ctx:        context.Background(),
+			err:        errors.New("failed to get statement: pq: canceling statement due to statement timeout"),
+			wantStatus: http.StatusGatewayTimeout,
+			wantLevel:  "WARN",
+		},
+		{
+			name:       "database lock timeout",
+			ctx:        context.Background(),
+			err:        errors.New("failed to lock account: pq: canceling statement due to lock timeout"),
+			wantStatus: http.StatusGatewayTimeout,
+			wantLevel:  "WARN",
+		},
+		{
+			name:       "other error",
+			ctx:        context.Background(),
+			err:        errors.New("connection refused"),
+			wantStatus: http.StatusInternalServerError,
+			wantLevel:  "ERROR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			ctx := logging.WithRequestID(logging.WithLogger(tt.ctx, logging.New(&logs, slog.LevelInfo)), "req-1")
+			r := httptest.NewRequest(http.MethodGet, "/accounts/1", nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+			writeServerError(w, r, tt.err)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantLevel == "" {
+				if logs.Len() > 0 {
+					t.Errorf("logged %s, want nothing", logs.String())
+				}
+				return
+			}
+			var record map[string]interface{}
+			if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+				t.Fatalf("log line is not JSON: %v: %s", err, logs.String())
+			}
+			if record["level"] != tt.wantLevel || record["request_id"] != "req-1" || record["error"] != tt.err.Error() {
+				t.Errorf("log record = %v, want level %s with the request ID and error", record, tt.wantLevel)
+			}
+		})
+	}
+}
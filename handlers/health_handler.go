@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"triplea-backend-assignment/models"
+)
+
+// HealthSource is what the readiness probe checks. *database.Store
+// implements it.
+type HealthSource interface {
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (int, error)
+}
+
+type HealthHandler struct {
+	source        HealthSource
+	schemaVersion int
+	timeout       time.Duration
+	shuttingDown  atomic.Bool
+}
+
+// NewHealthHandler checks source against schemaVersion, the migration
+// version the binary expects. timeout bounds every readiness probe.
+func NewHealthHandler(source HealthSource, schemaVersion int, timeout time.Duration) *HealthHandler {
+	return &HealthHandler{
+		source:        source,
+		schemaVersion: schemaVersion,
+		timeout:       timeout,
+	}
+}
+
+// SetShuttingDown makes the readiness probe fail, so load balancers stop
+// sending requests while in-flight ones drain.
+func (h *HealthHandler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Livez reports that the process is up. It checks no dependency, so a
+// database outage does not get the process restarted.
+func (h *HealthHandler) Livez(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeHealthReport(w, models.HealthReport{Status: models.HealthStatusOK})
+}
+
+// Readyz reports whether the process can serve requests: the database
+// answers within the timeout, its schema is at least the version the binary
+// expects, and shutdown has not started.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	report := models.HealthReport{Status: models.HealthStatusOK, Checks: map[string]models.HealthCheck{}}
+	record := func(name string, check func() error) {
+		start := time.Now()
+		err := check()
+		result := models.HealthCheck{
+			Status:     models.HealthStatusOK,
+			DurationMS: float64(time.Since(start)) / float64(time.Millisecond),
+		}
+		if err != nil {
+			result.Status = models.HealthStatusFail
+			result.Error = err.Error()
+			report.Status = models.HealthStatusFail
+		}
+		report.Checks[name] = result
+	}
+
+	record("shutdown", func() error {
+		if h.shuttingDown.Load() {
+			return fmt.Errorf("shutting down")
+		}
+		return nil
+	})
+	record("database", func() error {
+		return h.source.Ping(ctx)
+	})
+	record("migrations", func() error {
+		version, err := h.source.SchemaVersion(ctx)
+		if err != nil {
+			return err
+		}
+		// A newer schema is fine: during a rolling deploy the new release
+		// migrates while the old one still serves.
+		if version < h.schemaVersion {
+			return fmt.Errorf("schema version is %d, want %d", version, h.schemaVersion)
+		}
+		return nil
+	})
+
+	writeHealthReport(w, report)
+}
+
+func writeHealthReport(w http.ResponseWriter, report models.HealthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != models.HealthStatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"triplea-backend-assignment/models"
+)
+
+type fakeHealthSource struct {
+	pingErr    error
+	version    int
+	versionErr error
+	block      bool
+}
+
+func (s fakeHealthSource) Ping(ctx context.Context) error {
+	if s.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return s.pingErr
+}
+
+func (s fakeHealthSource) SchemaVersion(ctx context.Context) (int, error) {
+	return s.version, s.versionErr
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name         string
+		source       fakeHealthSource
+		shuttingDown bool
+		wantStatus   int
+		wantFailed   []string
+	}{
+		{name: "ready", source: fakeHealthSource{version: 8}, wantStatus: http.StatusOK},
+		{name: "newer schema", source: fakeHealthSource{version: 9}, wantStatus: http.StatusOK},
+		{name: "database down", source: fakeHealthSource{pingErr: errors.New("connection refused"), versionErr: errors.New("connection refused")},
+			wantStatus: http.StatusServiceUnavailable, wantFailed: []string{"database", "migrations"}},
+		{name: "database times out", source: fakeHealthSource{block: true, version: 8},
+			wantStatus: http.StatusServiceUnavailable, wantFailed: []string{"database"}},
+		{name: "migrations pending", source: fakeHealthSource{version: 7},
+			wantStatus: http.StatusServiceUnavailable, wantFailed: []string{"migrations"}},
+		{name: "shutting down", source: fakeHealthSource{version: 8}, shuttingDown: true,
+			wantStatus: http.StatusServiceUnavailable, wantFailed: []string{"shutdown"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHealthHandler(tt.source, 8, 20*time.Millisecond)
+			if tt.shuttingDown {
+				handler.SetShuttingDown()
+			}
+
+			rec := httptest.NewRecorder()
+			handler.Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Readyz() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var report models.HealthReport
+			if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			failed := map[string]bool{}
+			for name, check := range report.Checks {
+				if check.Status == models.HealthStatusFail {
+					failed[name] = true
+				}
+			}
+			if len(failed) != len(tt.wantFailed) {
+				t.Errorf("Readyz() checks = %+v, want failed %v", report.Checks, tt.wantFailed)
+			}
+			for _, name := range tt.wantFailed {
+				if !failed[name] {
+					t.Errorf("Readyz() check %s = %+v, want it failed", name, report.Checks[name])
+				}
+			}
+		})
+	}
+}
+
+func TestLivez(t *testing.T) {
+	// Liveness does not depend on the database.
+	handler := NewHealthHandler(fakeHealthSource{pingErr: errors.New("connection refused")}, 8, time.Second)
+	handler.SetShuttingDown()
+
+	rec := httptest.NewRecorder()
+	handler.Livez(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Livez() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
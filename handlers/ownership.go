@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"net/http"
+
+	"triplea-backend-assignment/auth"
+)
+
+// ownsAccount reports whether the principal of r may act on accountID.
+// Unauthenticated requests may not, so a route that forgets its auth
+// middleware fails closed.
+func ownsAccount(r *http.Request, accountID int64) bool {
+	principal := auth.FromContext(r.Context())
+	return principal != nil && principal.OwnsAccount(accountID)
+}
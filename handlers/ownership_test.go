@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"triplea-backend-assignment/auth"
+	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/repository"
+	"triplea-backend-assignment/service"
+)
+
+type nopTransferRecorder struct{}
+
+func (nopTransferRecorder) RecordTransfer(result string, amount float64) {}
+
+func TestAccountOwnership(t *testing.T) {
+	alice := &auth.Principal{Kind: auth.KindUser, Subject: "alice", AccountIDs: []int64{1}}
+	gateway := &auth.Principal{Kind: auth.KindService, Subject: "api_key:tak_gateway"}
+
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		get        string
+		transfer   string
+		wantStatus int
+	}{
+		{name: "user reads own account", principal: alice, get: "1", wantStatus: http.StatusOK},
+		{name: "user reads other account", principal: alice, get: "2", wantStatus: http.StatusForbidden},
+		{name: "user reads unknown account", principal: alice, get: "9", wantStatus: http.StatusForbidden},
+		{name: "service reads any account", principal: gateway, get: "2", wantStatus: http.StatusOK},
+		{name: "unauthenticated read", get: "1", wantStatus: http.StatusForbidden},
+		{name: "user debits own account", principal: alice,
+			transfer: `{"source_account_id": 1, "destination_account_id": 2, "amount": "5"}`, wantStatus: http.StatusCreated},
+		{name: "user debits other account", principal: alice,
+			transfer: `{"source_account_id": 2, "destination_account_id": 1, "amount": "5"}`, wantStatus: http.StatusForbidden},
+		{name: "service debits any account", principal: gateway,
+			transfer: `{"source_account_id": 2, "destination_account_id": 1, "amount": "5"}`, wantStatus: http.StatusCreated},
+		{name: "unauthenticated debit",
+			transfer: `{"source_account_id": 1, "destination_account_id": 2, "amount": "5"}`, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := repository.NewMemoryStore()
+			for _, accountID := range []int64{1, 2} {
+				if err := store.Accounts().Create(ctx, accountID, "100"); err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+			}
+			if tt.principal != nil {
+				ctx = auth.WithPrincipal(ctx, tt.principal)
+			}
+
+			rec := httptest.NewRecorder()
+			if tt.get != "" {
+				handler := NewAccountHandler(service.NewAccountService(store.Accounts()))
+				req := httptest.NewRequest(http.MethodGet, "/accounts/"+tt.get, nil).WithContext(ctx)
+				handler.GetAccount(rec, mux.SetURLVars(req, map[string]string{"account_id": tt.get}))
+			} else {
+				transactions := service.NewTransactionService(store, store.Transactions(), store.Accounts(), nopTransferRecorder{}, nil)
+				handler := NewTransactionHandler(transactions)
+				req := httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader(tt.transfer)).WithContext(ctx)
+				handler.CreateTransaction(rec, req)
+			}
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestReadOwnership(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	for _, accountID := range []int64{1, 2, 3} {
+		if err := store.Accounts().Create(ctx, accountID, "100"); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	transactions := service.NewTransactionService(store, store.Transactions(), store.Accounts(), nopTransferRecorder{}, nil)
+	// Transactions 1, 2 and 3: 2→3, 1→2 and 3→1.
+	for _, transfer := range [][2]int64{{2, 3}, {1, 2}, {3, 1}} {
+		req := &models.CreateTransactionRequest{SourceAccountID: transfer[0], DestinationAccountID: transfer[1], Amount: "5"}
+		if err := transactions.ProcessTransaction(ctx, req); err != nil {
+			t.Fatalf("ProcessTransaction(%d→%d) error = %v", transfer[0], transfer[1], err)
+		}
+	}
+	transactionHandler := NewTransactionHandler(transactions)
+	// The statement service is never reached when access is refused.
+	statementHandler := NewStatementHandler(nil)
+
+	alice := &auth.Principal{Kind: auth.KindUser, Subject: "alice", AccountIDs: []int64{1}}
+	gateway := &auth.Principal{Kind: auth.KindService, Subject: "api_key:tak_gateway"}
+
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		handler    http.HandlerFunc
+		path       string
+		vars       map[string]string
+		wantStatus int
+	}{
+		{name: "user lists own history", principal: alice, handler: transactionHandler.ListAccountTransactions,
+			path: "/accounts/1/transactions", vars: map[string]string{"account_id": "1"}, wantStatus: http.StatusOK},
+		{name: "user lists other history", principal: alice, handler: transactionHandler.ListAccountTransactions,
+			path: "/accounts/2/transactions", vars: map[string]string{"account_id": "2"}, wantStatus: http.StatusForbidden},
+		{name: "unauthenticated history", handler: transactionHandler.ListAccountTransactions,
+			path: "/accounts/1/transactions", vars: map[string]string{"account_id": "1"}, wantStatus: http.StatusForbidden},
+		{name: "user exports other statement", principal: alice, handler: statementHandler.GetStatement,
+			path: "/accounts/2/statement", vars: map[string]string{"account_id": "2"}, wantStatus: http.StatusForbidden},
+		{name: "user gets own debit", principal: alice, handler: transactionHandler.GetTransaction,
+			path: "/transactions/2", vars: map[string]string{"transaction_id": "2"}, wantStatus: http.StatusOK},
+		{name: "user gets own credit", principal: alice, handler: transactionHandler.GetTransaction,
+			path: "/transactions/3", vars: map[string]string{"transaction_id": "3"}, wantStatus: http.StatusOK},
+		{name: "user gets other transfer", principal: alice, handler: transactionHandler.GetTransaction,
+			path: "/transactions/1", vars: map[string]string{"transaction_id": "1"}, wantStatus: http.StatusNotFound},
+		{name: "service gets any transfer", principal: gateway, handler: transactionHandler.GetTransaction,
+			path: "/transactions/1", vars: map[string]string{"transaction_id": "1"}, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = auth.WithPrincipal(ctx, tt.principal)
+			}
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil).WithContext(ctx)
+			rec := httptest.NewRecorder()
+			tt.handler(rec, mux.SetURLVars(req, tt.vars))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusNotFound && !strings.Contains(rec.Body.String(), "transaction not found") {
+				t.Errorf("body = %q, want the not found message of a missing transaction", rec.Body.String())
+			}
+		})
+	}
+}
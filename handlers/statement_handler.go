@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"triplea-backend-assignment/logging"
+	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/service"
+)
+
+type StatementHandler struct {
+	statementService *service.StatementService
+}
+
+func NewStatementHandler(statementService *service.StatementService) *StatementHandler {
+	return &StatementHandler{
+		statementService: statementService,
+	}
+}
+
+func (h *StatementHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	accountID, err := strconv.ParseInt(vars["account_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid account_id", http.StatusBadRequest)
+		return
+	}
+
+	if !ownsAccount(r, accountID) {
+		http.Error(w, "Access to this account is not allowed", http.StatusForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+	req := models.StatementRequest{
+		AccountID: accountID,
+		From:      query.Get("from"),
+		To:        query.Get("to"),
+		Format:    query.Get("format"),
+	}
+	if req.Format == "" {
+		req.Format = models.StatementFormatJSON
+	}
+
+	var writer streamingStatementWriter = newJSONStatementWriter(w)
+	if req.Format == models.StatementFormatCSV {
+		writer = newCSVStatementWriter(w)
+	}
+
+	if err := h.statementService.WriteStatement(r.Context(), &req, writer); err != nil {
+		if writer.Started() {
+			// The status line is already on the wire; the truncated body is
+			// the only signal left to the client.
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "statement aborted",
+				"account_id", accountID, "error", err.Error())
+			return
+		}
+		if isValidationError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if isAccountNotFoundError(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeServerError(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/service"
+)
+
+const statementFlushInterval = 100
+
+type streamingStatementWriter interface {
+	service.StatementWriter
+	Started() bool
+}
+
+// statementWriter streams a statement to the client. Headers are only sent
+// with the opening balance, so errors raised before that can still be
+// reported with a proper status code.
+type statementWriter struct {
+	w       http.ResponseWriter
+	started bool
+	lines   int
+}
+
+func (s *statementWriter) start(req *models.StatementRequest, contentType string) {
+	s.w.Header().Set("Content-Type", contentType)
+	s.w.Header().Set("Content-Disposition", fmt.Sprintf(
+		"attachment; filename=\"statement-%d-%s-%s.%s\"", req.AccountID, req.From, req.To, req.Format))
+	s.w.WriteHeader(http.StatusOK)
+	s.started = true
+}
+
+func (s *statementWriter) Started() bool {
+	return s.started
+}
+
+func (s *statementWriter) flush() {
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+type csvStatementWriter struct {
+	statementWriter
+	csv *csv.Writer
+}
+
+func newCSVStatementWriter(w http.ResponseWriter) *csvStatementWriter {
+	return &csvStatementWriter{
+		statementWriter: statementWriter{w: w},
+		csv:             csv.NewWriter(w),
+	}
+}
+
+func (c *csvStatementWriter) WriteOpening(req *models.StatementRequest, opening models.Decimal) error {
+	c.start(req, "text/csv")
+	c.csv.Write([]string{"entry_type", "transaction_id", "created_at", "counterparty_account_id", "direction", "amount", "running_balance"})
+	c.csv.Write([]string{"opening_balance", "", req.From, "", "", "", opening.String()})
+	return c.csv.Error()
+}
+
+func (c *csvStatementWriter) WriteLine(line *models.StatementLine) error {
+	c.csv.Write([]string{
+		"transaction",
+		strconv.FormatInt(line.TransactionID, 10),
+		line.CreatedAt.Format(time.RFC3339Nano),
+		strconv.FormatInt(line.CounterpartyAccountID, 10),
+		line.Direction,
+		line.Amount.String(),
+		line.RunningBalance.String(),
+	})
+	c.lines++
+	if c.lines%statementFlushInterval == 0 {
+		c.csv.Flush()
+		c.flush()
+	}
+	return c.csv.Error()
+}
+
+func (c *csvStatementWriter) WriteClosing(closing models.Decimal) error {
+	c.csv.Write([]string{"closing_balance", "", "", "", "", "", closing.String()})
+	c.csv.Flush()
+	return c.csv.Error()
+}
+
+type jsonStatementWriter struct {
+	statementWriter
+}
+
+func newJSONStatementWriter(w http.ResponseWriter) *jsonStatementWriter {
+	return &jsonStatementWriter{
+		statementWriter: statementWriter{w: w},
+	}
+}
+
+func (j *jsonStatementWriter) WriteOpening(req *models.StatementRequest, opening models.Decimal) error {
+	j.start(req, "application/json")
+	_, err := fmt.Fprintf(j.w, `{"account_id":%d,"from":%q,"to":%q,"opening_balance":%q,"transactions":[`,
+		req.AccountID, req.From, req.To, opening.String())
+	return err
+}
+
+func (j *jsonStatementWriter) WriteLine(line *models.StatementLine) error {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	if j.lines > 0 {
+		if _, err := j.w.Write([]byte(",")); err != nil {
+			return err
+		}
+	}
+	if _, err := j.w.Write(data); err != nil {
+		return err
+	}
+	j.lines++
+	if j.lines%statementFlushInterval == 0 {
+		j.flush()
+	}
+	return nil
+}
+
+func (j *jsonStatementWriter) WriteClosing(closing models.Decimal) error {
+	_, err := fmt.Fprintf(j.w, `],"closing_balance":%q}`+"\n", closing.String())
+	return err
+}
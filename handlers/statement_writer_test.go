@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"triplea-backend-assignment/models"
+)
+
+func writeTestStatement(t *testing.T, writer streamingStatementWriter, req *models.StatementRequest) {
+	t.Helper()
+	if err := writer.WriteOpening(req, "100.00"); err != nil {
+		t.Fatalf("WriteOpening() error = %v", err)
+	}
+	lines := []*models.StatementLine{
+		{TransactionID: 1, CreatedAt: time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC), CounterpartyAccountID: 456,
+			Direction: models.StatementDirectionDebit, Amount: "30.00", RunningBalance: "70.00"},
+		{TransactionID: 2, CreatedAt: time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC), CounterpartyAccountID: 789,
+			Direction: models.StatementDirectionCredit, Amount: "5.50", RunningBalance: "75.50"},
+	}
+	for _, line := range lines {
+		if err := writer.WriteLine(line); err != nil {
+			t.Fatalf("WriteLine() error = %v", err)
+		}
+	}
+	if err := writer.WriteClosing("75.50"); err != nil {
+		t.Fatalf("WriteClosing() error = %v", err)
+	}
+}
+
+func TestJSONStatementWriter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	req := &models.StatementRequest{AccountID: 123, From: "2024-01-01", To: "2024-01-31", Format: "json"}
+	writeTestStatement(t, newJSONStatementWriter(recorder), req)
+
+	var statement struct {
+		AccountID      int64                  `json:"account_id"`
+		OpeningBalance string                 `json:"opening_balance"`
+		Transactions   []models.StatementLine `json:"transactions"`
+		ClosingBalance string                 `json:"closing_balance"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &statement); err != nil {
+		t.Fatalf("statement is not valid JSON: %v\n%s", err, recorder.Body.String())
+	}
+	if statement.AccountID != 123 || statement.OpeningBalance != "100.00" || statement.ClosingBalance != "75.50" {
+		t.Errorf("unexpected statement summary: %+v", statement)
+	}
+	if len(statement.Transactions) != 2 || statement.Transactions[1].RunningBalance != "75.50" {
+		t.Errorf("unexpected statement transactions: %+v", statement.Transactions)
+	}
+	if got := recorder.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+}
+
+func TestCSVStatementWriter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	req := &models.StatementRequest{AccountID: 123, From: "2024-01-01", To: "2024-01-31", Format: "csv"}
+	writeTestStatement(t, newCSVStatementWriter(recorder), req)
+
+	records, err := csv.NewReader(strings.NewReader(recorder.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("statement is not valid CSV: %v", err)
+	}
+	if len(records) != 5 {
+		t.Fatalf("got %d records, want 5 (header, opening, 2 transactions, closing)", len(records))
+	}
+	if records[1][0] != "opening_balance" || records[1][6] != "100.00" {
+		t.Errorf("unexpected opening record: %v", records[1])
+	}
+	if records[2][4] != models.StatementDirectionDebit || records[2][3] != "456" {
+		t.Errorf("unexpected transaction record: %v", records[2])
+	}
+	if records[4][0] != "closing_balance" || records[4][6] != "75.50" {
+		t.Errorf("unexpected closing record: %v", records[4])
+	}
+}
@@ -2,9 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 
+	"github.com/gorilla/mux"
 	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/ratelimit"
+	"triplea-backend-assignment/repository"
 	"triplea-backend-assignment/service"
 )
 
@@ -30,7 +35,18 @@ func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	if err := h.transactionService.ProcessTransaction(&req); err != nil {
+	if !ownsAccount(r, req.SourceAccountID) {
+		http.Error(w, "Debiting this source account is not allowed", http.StatusForbidden)
+		return
+	}
+
+	if err := h.transactionService.ProcessTransaction(r.Context(), &req); err != nil {
+		var limited *ratelimit.ExceededError
+		if errors.As(err, &limited) {
+			limited.Decision.SetHeaders(w.Header())
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
 		if isValidationError(err) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -43,10 +59,101 @@ func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Re
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeServerError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
 }
 
+func (h *TransactionHandler) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	transactionID, err := strconv.ParseInt(vars["transaction_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid transaction_id", http.StatusBadRequest)
+		return
+	}
+
+	transaction, err := h.transactionService.GetTransaction(r.Context(), transactionID)
+	if err != nil {
+		if isValidationError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if isTransactionNotFoundError(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeServerError(w, r, err)
+		return
+	}
+
+	// A transfer between other people's accounts is reported as missing, so
+	// that users cannot probe which IDs exist.
+	if !ownsAccount(r, transaction.SourceAccountID) && !ownsAccount(r, transaction.DestinationAccountID) {
+		http.Error(w, repository.ErrTransactionNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transaction)
+}
+
+func (h *TransactionHandler) ListAccountTransactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	vars := mux.Vars(r)
+	accountID, err := strconv.ParseInt(vars["account_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid account_id", http.StatusBadRequest)
+		return
+	}
+
+	if !ownsAccount(r, accountID) {
+		http.Error(w, "Access to this account is not allowed", http.StatusForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+	req := models.TransactionHistoryRequest{
+		AccountID: accountID,
+		Status:    query.Get("status"),
+	}
+	if value := query.Get("before_id"); value != "" {
+		if req.BeforeID, err = strconv.ParseInt(value, 10, 64); err != nil {
+			http.Error(w, "Invalid before_id", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if req.Limit, err = strconv.Atoi(value); err != nil || req.Limit == 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	history, err := h.transactionService.ListAccountTransactions(r.Context(), &req)
+	if err != nil {
+		if isValidationError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if isAccountNotFoundError(err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"triplea-backend-assignment/auth"
+	"triplea-backend-assignment/config"
+	"triplea-backend-assignment/ratelimit"
+	"triplea-backend-assignment/repository"
+	"triplea-backend-assignment/service"
+)
+
+func TestCreateTransaction_DebitRateLimit(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	for _, accountID := range []int64{1, 2} {
+		if err := store.Accounts().Create(ctx, accountID, "100"); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	debits := ratelimit.NewLimiter(config.RateLimit{Requests: 1, Per: time.Minute})
+	handler := NewTransactionHandler(service.NewTransactionService(store, store.Transactions(), store.Accounts(), nopTransferRecorder{}, debits))
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{Kind: auth.KindService})
+
+	for i, want := range []int{http.StatusCreated, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodPost, "/transactions",
+			strings.NewReader(`{"source_account_id": 1, "destination_account_id": 2, "amount": "5"}`)).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		handler.CreateTransaction(rec, req)
+
+		if rec.Code != want {
+			t.Fatalf("request #%d status = %d, want %d: %s", i+1, rec.Code, want, rec.Body.String())
+		}
+		if want != http.StatusTooManyRequests {
+			continue
+		}
+		if got := rec.Header().Get("Retry-After"); got != "60" {
+			t.Errorf("Retry-After = %q, want %q", got, "60")
+		}
+		if got := rec.Header().Get("RateLimit-Remaining"); got != "0" {
+			t.Errorf("RateLimit-Remaining = %q, want %q", got, "0")
+		}
+	}
+}
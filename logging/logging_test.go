@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestWithRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := WithLogger(context.Background(), New(&buf, slog.LevelInfo))
+	ctx = WithRequestID(ctx, "req-123")
+
+	if got := RequestID(ctx); got != "req-123" {
+		t.Errorf("RequestID() = %q, want %q", got, "req-123")
+	}
+	FromContext(ctx).InfoContext(ctx, "transfer completed", "transaction_id", 7)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("log line is not JSON: %v: %s", err, buf.String())
+	}
+	if record["request_id"] != "req-123" || record["msg"] != "transfer completed" || record["transaction_id"] != float64(7) {
+		t.Errorf("log record = %v, want the message with request_id and transaction_id", record)
+	}
+}
+
+func TestFromContextDefault(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("FromContext() without a logger did not return the default logger")
+	}
+	if got := RequestID(context.Background()); got != "" {
+		t.Errorf("RequestID() outside a request = %q, want empty", got)
+	}
+}
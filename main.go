@@ -1,59 +1,276 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
+	"triplea-backend-assignment/auth"
 	"triplea-backend-assignment/config"
 	"triplea-backend-assignment/database"
 	"triplea-backend-assignment/handlers"
+	"triplea-backend-assignment/logging"
+	"triplea-backend-assignment/metrics"
 	"triplea-backend-assignment/middleware"
+	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/ratelimit"
 	"triplea-backend-assignment/repository"
 	"triplea-backend-assignment/service"
+	"triplea-backend-assignment/tracing"
 )
 
 func main() {
+	slog.SetDefault(logging.New(os.Stdout, slog.LevelInfo))
+	if err := run(); err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+}
+
+// run returns instead of exiting so that its deferred cleanup, closing the
+// database pool in particular, always runs.
+func run() error {
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	slog.SetDefault(logging.New(os.Stdout, cfg.Log.Level))
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	// Registered first so it runs last, after everything that makes spans.
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Warn("failed to flush spans", "error", err.Error())
+		}
+	}()
+
+	// The first SIGINT/SIGTERM starts a graceful shutdown; a second one
+	// kills the process.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	store, err := database.Open(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer store.Close()
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(ctx, store, os.Args[2:]); err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+		return nil
+	}
+	if len(os.Args) > 1 && os.Args[1] == "archive" {
+		if err := runArchiveCommand(ctx, cfg, store, os.Args[2:]); err != nil {
+			return fmt.Errorf("archive failed: %w", err)
+		}
+		return nil
 	}
 
-	if err := database.Connect(cfg); err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	if cfg.Database.AutoMigrate {
+		if err := store.MigrateUp(ctx); err != nil {
+			return fmt.Errorf("failed to run migrations: %w", err)
+		}
 	}
-	defer database.Close()
 
-	if err := database.Migrate(); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	txManager := repository.NewPostgresTxManager(store)
+	accountRepo, err := repository.NewPostgresAccountRepository(ctx, store)
+	if err != nil {
+		return fmt.Errorf("failed to prepare queries: %w", err)
+	}
+	transactionRepo, err := repository.NewPostgresTransactionRepository(ctx, store)
+	if err != nil {
+		return fmt.Errorf("failed to prepare queries: %w", err)
+	}
+	businessDayRepo, err := repository.NewBusinessDayRepository(ctx, store)
+	if err != nil {
+		return fmt.Errorf("failed to prepare queries: %w", err)
+	}
+	statementRepo, err := repository.NewStatementRepository(ctx, store)
+	if err != nil {
+		return fmt.Errorf("failed to prepare queries: %w", err)
+	}
+	reportRepo, err := repository.NewReportRepository(ctx, store)
+	if err != nil {
+		return fmt.Errorf("failed to prepare queries: %w", err)
+	}
+	partitionRepo := repository.NewPartitionRepository(store)
+	apiKeyRepo, err := repository.NewPostgresAPIKeyRepository(ctx, store)
+	if err != nil {
+		return fmt.Errorf("failed to prepare queries: %w", err)
+	}
+	nonceRepo, err := repository.NewNonceRepository(ctx, store)
+	if err != nil {
+		return fmt.Errorf("failed to prepare queries: %w", err)
 	}
 
-	accountRepo := repository.NewAccountRepository()
-	transactionRepo := repository.NewTransactionRepository()
+	appMetrics := metrics.New()
+	appMetrics.RegisterDatabase(store)
 
 	accountService := service.NewAccountService(accountRepo)
-	transactionService := service.NewTransactionService(transactionRepo, accountRepo)
+	var debitLimiter *ratelimit.Limiter
+	if cfg.RateLimits.AccountDebits.Enabled() {
+		debitLimiter = ratelimit.NewLimiter(cfg.RateLimits.AccountDebits)
+	}
+	transactionService := service.NewTransactionService(txManager, transactionRepo, accountRepo, appMetrics, debitLimiter)
+	businessDayService := service.NewBusinessDayService(businessDayRepo)
+	pendingSweeper := service.NewPendingSweeper(
+		txManager,
+		transactionRepo,
+		accountRepo,
+		cfg.Sweeper.Interval,
+		cfg.Sweeper.PendingMaxAge,
+		cfg.Sweeper.BatchSize,
+	)
+	statementService := service.NewStatementService(statementRepo)
+	reportService := service.NewReportService(reportRepo)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, cfg.Auth.AdminAPIKey)
+	partitionMaintainer := service.NewPartitionMaintainer(partitionRepo, cfg.Partitions.MaintenanceInterval, cfg.Partitions.MonthsAhead)
+	noncePruner := service.NewNoncePruner(nonceRepo, time.Minute)
+
+	workers := newBackgroundWorkers()
+	workers.Go(pendingSweeper.Run)
+	workers.Go(partitionMaintainer.Run)
+	workers.Go(noncePruner.Run)
+	workers.Go(func(ctx context.Context) {
+		store.MonitorReplicas(ctx, cfg.Database.ReplicaCheckInterval, cfg.Database.ReplicaMaxLag)
+	})
 
 	accountHandler := handlers.NewAccountHandler(accountService)
 	transactionHandler := handlers.NewTransactionHandler(transactionService)
+	businessDayHandler := handlers.NewBusinessDayHandler(businessDayService)
+	statementHandler := handlers.NewStatementHandler(statementService)
+	reportHandler := handlers.NewReportHandler(reportService)
+	databaseHandler := handlers.NewDatabaseHandler(store)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	schemaVersion, err := database.LatestVersion()
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	healthHandler := handlers.NewHealthHandler(store, schemaVersion, cfg.Server.ReadinessTimeout)
+
+	// A nil *JWTVerifier must not end up in the interface, or bearer tokens
+	// would not be rejected.
+	var tokens middleware.TokenVerifier
+	if cfg.Auth.JWT.Enabled() {
+		jwtVerifier, err := auth.NewJWTVerifier(cfg.Auth.JWT)
+		if err != nil {
+			return fmt.Errorf("failed to set up JWT verification: %w", err)
+		}
+		tokens = jwtVerifier
+	}
+	authn := middleware.NewAuth(apiKeyService, tokens)
+	limits := middleware.NewRateLimit(cfg.RateLimits)
+	signatures := middleware.NewSignatureVerifier(cfg.Auth.Signatures.ClientSecrets, cfg.Auth.Signatures.MaxClockSkew, nonceRepo)
 
 	router := mux.NewRouter()
 
+	router.Use(middleware.RequestID)
+	router.Use(middleware.Tracing)
 	router.Use(middleware.LoggingMiddleware)
+	router.Use(middleware.Metrics(appMetrics))
+	router.Use(middleware.Recover(appMetrics))
+	router.Use(middleware.Consistency)
+
+	// route applies the route's timeout and the caller's IP limit, requires
+	// a principal with scope and applies the principal's rate limit.
+	route := func(scope string, timeout time.Duration, handler http.HandlerFunc) http.Handler {
+		return middleware.Timeout(timeout)(limits.LimitIP(authn.Require(scope)(limits.Limit(handler))))
+	}
+	// signedRoute is a route that also accepts HMAC-signed requests.
+	signedRoute := func(scope string, timeout time.Duration, handler http.HandlerFunc) http.Handler {
+		return middleware.Timeout(timeout)(limits.LimitIP(signatures.Verify(authn.Require(scope)(limits.Limit(handler)))))
+	}
+	timeouts := cfg.Timeouts
 
-	router.HandleFunc("/accounts", accountHandler.CreateAccount).Methods("POST")
-	router.HandleFunc("/accounts/{account_id}", accountHandler.GetAccount).Methods("GET")
-	router.HandleFunc("/transactions", transactionHandler.CreateTransaction).Methods("POST")
+	router.Handle("/accounts", route(models.ScopeAccountsWrite, timeouts.Default, accountHandler.CreateAccount)).Methods("POST")
+	router.Handle("/accounts/{account_id}", route(models.ScopeAccountsRead, timeouts.Default, accountHandler.GetAccount)).Methods("GET")
+	router.Handle("/accounts/{account_id}/statement", route(models.ScopeAccountsRead, timeouts.Statement, statementHandler.GetStatement)).Methods("GET")
+	router.Handle("/accounts/{account_id}/transactions", route(models.ScopeAccountsRead, timeouts.Default, transactionHandler.ListAccountTransactions)).Methods("GET")
+	router.Handle("/transactions", signedRoute(models.ScopeTransfersWrite, timeouts.Default, transactionHandler.CreateTransaction)).Methods("POST")
+	router.Handle("/transactions/{transaction_id}", route(models.ScopeAccountsRead, timeouts.Default, transactionHandler.GetTransaction)).Methods("GET")
+	router.Handle("/business-days/close", route(models.ScopeAdmin, timeouts.BusinessDayClose, businessDayHandler.CloseBusinessDay)).Methods("POST")
+	router.Handle("/business-days/{business_date}/snapshots", route(models.ScopeAdmin, timeouts.Default, businessDayHandler.GetSnapshots)).Methods("GET")
+	router.Handle("/admin/reports/trial-balance", route(models.ScopeAdmin, timeouts.Report, reportHandler.GetTrialBalance)).Methods("GET")
+	router.Handle("/admin/db/stats", route(models.ScopeAdmin, timeouts.Default, databaseHandler.GetPoolStats)).Methods("GET")
+	router.Handle("/admin/db/queries", route(models.ScopeAdmin, timeouts.Default, databaseHandler.GetQueryStats)).Methods("GET")
+	router.Handle("/admin/api-keys", route(models.ScopeAdmin, timeouts.Default, apiKeyHandler.CreateAPIKey)).Methods("POST")
+	router.Handle("/admin/api-keys", route(models.ScopeAdmin, timeouts.Default, apiKeyHandler.ListAPIKeys)).Methods("GET")
+	router.Handle("/admin/api-keys/{key_id}", route(models.ScopeAdmin, timeouts.Default, apiKeyHandler.RevokeAPIKey)).Methods("DELETE")
 
-	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	}).Methods("GET")
+	// Probes and metrics scraping stay open.
+	router.Handle("/metrics", appMetrics.Handler()).Methods("GET")
+	router.HandleFunc("/livez", healthHandler.Livez).Methods("GET")
+	router.HandleFunc("/readyz", healthHandler.Readyz).Methods("GET")
+	if err := checkRateLimitRoutes(router, cfg.RateLimits.Routes); err != nil {
+		workers.Stop(context.Background())
+		return err
+	}
 
+	server := &http.Server{
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
 	serverAddr := cfg.GetServerAddress()
-	log.Printf("Server starting on %s", serverAddr)
-	if err := http.ListenAndServe(serverAddr, router); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	listener, err := net.Listen("tcp", serverAddr)
+	if err != nil {
+		workers.Stop(context.Background())
+		return fmt.Errorf("failed to start server: %w", err)
+	}
+	slog.Info("server starting", "addr", serverAddr)
+	plan := shutdownPlan{
+		notReady: healthHandler.SetShuttingDown,
+		delay:    cfg.Server.ShutdownDelay,
+		grace:    cfg.Server.ShutdownGracePeriod,
 	}
+	if err := serve(ctx, server, listener, plan, workers); err != nil {
+		return err
+	}
+	slog.Info("server stopped")
+	return nil
 }
 
+// checkRateLimitRoutes rejects a limit for a route that does not exist,
+// which would otherwise be ignored.
+func checkRateLimitRoutes(router *mux.Router, limits map[string]config.RateLimit) error {
+	routes := map[string]bool{}
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, _ := route.GetMethods()
+		for _, method := range methods {
+			routes[method+" "+template] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list routes: %w", err)
+	}
+	for route := range limits {
+		if !routes[route] {
+			return fmt.Errorf("RATE_LIMIT_ROUTES: no route %s", route)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"triplea-backend-assignment/config"
+)
+
+func TestCheckRateLimitRoutes(t *testing.T) {
+	router := mux.NewRouter()
+	nop := func(w http.ResponseWriter, r *http.Request) {}
+	router.HandleFunc("/transactions", nop).Methods("POST")
+	router.HandleFunc("/accounts/{account_id}", nop).Methods("GET")
+
+	limit := config.RateLimit{Requests: 1, Per: time.Second}
+	tests := []struct {
+		route   string
+		wantErr bool
+	}{
+		{route: "POST /transactions"},
+		{route: "GET /accounts/{account_id}"},
+		{route: "GET /transactions", wantErr: true},
+		{route: "GET /accounts/1", wantErr: true},
+	}
+	for _, tt := range tests {
+		err := checkRateLimitRoutes(router, map[string]config.RateLimit{tt.route: limit})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkRateLimitRoutes(%s) error = %v, want error: %v", tt.route, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics owns a registry rather than using the global one, so tests can
+// create as many as they like.
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	panics          *prometheus.CounterVec
+	transfers       *prometheus.CounterVec
+	transferAmount  *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests served, by route template, method and status.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency, by route template, method and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		panics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_panics_total",
+			Help: "Handler panics recovered, by route template and method.",
+		}, []string{"route", "method"}),
+		transfers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "transfers_total",
+			Help: "Transfer attempts, by result.",
+		}, []string{"result"}),
+		transferAmount: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "transfer_amount",
+			Help:    "Amounts of transfer attempts, by result.",
+			Buckets: prometheus.ExponentialBuckets(1, 10, 8),
+		}, []string{"result"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.panics,
+		m.transfers,
+		m.transferAmount,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a served request. route is the route template,
+// e.g. /accounts/{account_id}, so IDs do not explode the label values.
+func (m *Metrics) ObserveRequest(route, method string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(route, method, code).Inc()
+	m.requestDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
+
+// RecordPanic records a handler panic that was recovered.
+func (m *Metrics) RecordPanic(route, method string) {
+	m.panics.WithLabelValues(route, method).Inc()
+}
+
+// RecordTransfer records the result of a transfer attempt.
+func (m *Metrics) RecordTransfer(result string, amount float64) {
+	m.transfers.WithLabelValues(result).Inc()
+	m.transferAmount.WithLabelValues(result).Observe(amount)
+}
+
+// DatabaseSource is what the database metrics are read from.
+// *database.Store implements it.
+type DatabaseSource interface {
+	Pools() map[string]*sql.DB
+	ConnectRetries() int64
+}
+
+// RegisterDatabase exports the statistics of every connection pool, labelled
+// db_name (primary or the replica host), and the startup connection retries.
+func (m *Metrics) RegisterDatabase(source DatabaseSource) {
+	for name, db := range source.Pools() {
+		m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+	}
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name: "db_connect_retries_total",
+		Help: "Failed attempts to reach the database at startup that were retried.",
+	}, func() float64 {
+		return float64(source.ConnectRetries())
+	}))
+}
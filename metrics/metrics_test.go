@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+type fakeDatabase struct {
+	pools map[string]*sql.DB
+}
+
+func (d fakeDatabase) Pools() map[string]*sql.DB { return d.pools }
+
+func (d fakeDatabase) ConnectRetries() int64 { return 2 }
+
+func TestHandler(t *testing.T) {
+	// sql.Open does not connect; the pool only has to report its stats.
+	db, err := sql.Open("postgres", "host=localhost")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+
+	m := New()
+	m.RegisterDatabase(fakeDatabase{pools: map[string]*sql.DB{"primary": db}})
+	m.ObserveRequest("/accounts/{account_id}", http.MethodGet, http.StatusOK, 30*time.Millisecond)
+	m.ObserveRequest("/accounts/{account_id}", http.MethodGet, http.StatusOK, 70*time.Millisecond)
+	m.RecordPanic("/accounts/{account_id}", http.MethodGet)
+	m.RecordTransfer("completed", 250)
+	m.RecordTransfer("insufficient_funds", 900)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/accounts/{account_id}",status="200"} 2`,
+		`http_request_duration_seconds_count{method="GET",route="/accounts/{account_id}",status="200"} 2`,
+		`http_panics_total{method="GET",route="/accounts/{account_id}"} 1`,
+		`transfers_total{result="completed"} 1`,
+		`transfers_total{result="insufficient_funds"} 1`,
+		`transfer_amount_bucket{result="completed",le="1000"} 1`,
+		`go_sql_max_open_connections{db_name="primary"} 0`,
+		`db_connect_retries_total 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output is missing %s", want)
+		}
+	}
+}
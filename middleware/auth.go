@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"triplea-backend-assignment/auth"
+	"triplea-backend-assignment/logging"
+	"triplea-backend-assignment/models"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// Authenticator looks up API keys. It returns nil, without an error, for a
+// key that is unknown or revoked. *service.APIKeyService implements it.
+type Authenticator interface {
+	Authenticate(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+// TokenVerifier verifies the bearer tokens of end users.
+// *auth.JWTVerifier implements it.
+type TokenVerifier interface {
+	Verify(token string) (*auth.Principal, error)
+}
+
+// Auth authenticates requests with an API key in the X-API-Key header, for
+// services, or a JWT in the Authorization header, for end users.
+type Auth struct {
+	apiKeys Authenticator
+	tokens  TokenVerifier
+}
+
+// NewAuth returns an Auth that rejects bearer tokens if tokens is nil.
+func NewAuth(apiKeys Authenticator, tokens TokenVerifier) *Auth {
+	return &Auth{apiKeys: apiKeys, tokens: tokens}
+}
+
+// Require lets a request through only if it is authenticated and its
+// principal has scope, or the admin scope. The principal is put in the
+// request context, see auth.FromContext, and its subject is added to the
+// request logger as principal. A principal already in the context, such as
+// one set by SignatureVerifier, is used as is.
+func (a *Auth) Require(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			principal, ok := a.authenticate(w, r)
+			if !ok {
+				return
+			}
+
+			ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("principal", principal.Subject))
+			if !principal.HasScope(scope) {
+				logging.FromContext(ctx).WarnContext(ctx, "principal lacks scope", "scope", scope)
+				http.Error(w, "Missing scope "+scope, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(ctx, principal)))
+		})
+	}
+}
+
+// authenticate returns the principal of r, or writes the error response and
+// returns false.
+func (a *Auth) authenticate(w http.ResponseWriter, r *http.Request) (*auth.Principal, bool) {
+	ctx := r.Context()
+	if principal := auth.FromContext(ctx); principal != nil {
+		return principal, true
+	}
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		apiKey, err := a.apiKeys.Authenticate(ctx, key)
+		if err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "failed to authenticate API key", "error", err.Error())
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return nil, false
+		}
+		if apiKey == nil {
+			http.Error(w, "Invalid API key", http.StatusUnauthorized)
+			return nil, false
+		}
+		return auth.FromAPIKey(apiKey), true
+	}
+
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer`)
+		http.Error(w, "API key or bearer token required", http.StatusUnauthorized)
+		return nil, false
+	}
+	if a.tokens == nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, "Bearer tokens are not accepted", http.StatusUnauthorized)
+		return nil, false
+	}
+	principal, err := a.tokens.Verify(token)
+	if err != nil {
+		logging.FromContext(ctx).InfoContext(ctx, "rejected bearer token", "error", err.Error())
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return nil, false
+	}
+	return principal, true
+}
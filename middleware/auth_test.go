@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"triplea-backend-assignment/auth"
+	"triplea-backend-assignment/config"
+	"triplea-backend-assignment/models"
+)
+
+type fakeAuthenticator map[string]*models.APIKey
+
+func (a fakeAuthenticator) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	if key == "broken" {
+		return nil, errors.New("connection refused")
+	}
+	return a[key], nil
+}
+
+type fakeTokenVerifier map[string]*auth.Principal
+
+func (v fakeTokenVerifier) Verify(token string) (*auth.Principal, error) {
+	if principal, ok := v[token]; ok {
+		return principal, nil
+	}
+	return nil, errors.New("signature is invalid")
+}
+
+func TestAuthRequire(t *testing.T) {
+	apiKeys := fakeAuthenticator{
+		"reader": {ID: 1, Prefix: "tak_reader", Scopes: []string{models.ScopeAccountsRead}},
+		"admin":  {ID: 2, Prefix: "tak_admin", Scopes: []string{models.ScopeAdmin}},
+	}
+	tokens := fakeTokenVerifier{
+		"alice":   {Kind: auth.KindUser, Subject: "alice", Scopes: []string{models.ScopeAccountsRead}, AccountIDs: []int64{7}},
+		"mallory": {Kind: auth.KindUser, Subject: "mallory", Scopes: []string{models.ScopeAdmin}},
+	}
+
+	tests := []struct {
+		name          string
+		tokens        TokenVerifier
+		principal     *auth.Principal
+		apiKey        string
+		authorization string
+		scope         string
+		wantStatus    int
+		wantSubject   string
+	}{
+		{name: "no credentials", tokens: tokens, scope: models.ScopeAccountsRead, wantStatus: http.StatusUnauthorized},
+		{name: "unknown API key", tokens: tokens, apiKey: "guess", scope: models.ScopeAccountsRead, wantStatus: http.StatusUnauthorized},
+		{name: "API key lookup fails", tokens: tokens, apiKey: "broken", scope: models.ScopeAccountsRead, wantStatus: http.StatusInternalServerError},
+		{name: "API key missing scope", tokens: tokens, apiKey: "reader", scope: models.ScopeTransfersWrite, wantStatus: http.StatusForbidden},
+		{name: "API key with scope", tokens: tokens, apiKey: "reader", scope: models.ScopeAccountsRead,
+			wantStatus: http.StatusOK, wantSubject: "api_key:tak_reader"},
+		{name: "admin API key", tokens: tokens, apiKey: "admin", scope: models.ScopeTransfersWrite,
+			wantStatus: http.StatusOK, wantSubject: "api_key:tak_admin"},
+		{name: "valid token", tokens: tokens, authorization: "Bearer alice", scope: models.ScopeAccountsRead,
+			wantStatus: http.StatusOK, wantSubject: "alice"},
+		{name: "token missing scope", tokens: tokens, authorization: "Bearer alice", scope: models.ScopeTransfersWrite, wantStatus: http.StatusForbidden},
+		{name: "token with admin scope", tokens: tokens, authorization: "Bearer mallory", scope: models.ScopeAdmin, wantStatus: http.StatusForbidden},
+		{name: "invalid token", tokens: tokens, authorization: "Bearer forged", scope: models.ScopeAccountsRead, wantStatus: http.StatusUnauthorized},
+		{name: "not a bearer token", tokens: tokens, authorization: "Basic YWxpY2U6c2VjcmV0", scope: models.ScopeAccountsRead, wantStatus: http.StatusUnauthorized},
+		{name: "tokens not accepted", authorization: "Bearer alice", scope: models.ScopeAccountsRead, wantStatus: http.StatusUnauthorized},
+		{name: "signed request", principal: &auth.Principal{Kind: auth.KindService, Subject: "hmac:gateway", Scopes: []string{models.ScopeTransfersWrite}},
+			scope: models.ScopeTransfersWrite, wantStatus: http.StatusOK, wantSubject: "hmac:gateway"},
+		{name: "signed request missing scope", principal: &auth.Principal{Kind: auth.KindService, Subject: "hmac:gateway", Scopes: []string{models.ScopeTransfersWrite}},
+			scope: models.ScopeAccountsRead, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *auth.Principal
+			handler := NewAuth(apiKeys, tt.tokens).Require(tt.scope)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = auth.FromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/accounts/7", nil)
+			if tt.principal != nil {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), tt.principal))
+			}
+			if tt.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tt.apiKey)
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if rec.Code == http.StatusUnauthorized && tt.apiKey == "" && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without a WWW-Authenticate header")
+			}
+			if tt.wantSubject == "" {
+				if got != nil {
+					t.Errorf("handler ran with principal %+v, want it not to run", got)
+				}
+				return
+			}
+			if got == nil || got.Subject != tt.wantSubject {
+				t.Errorf("auth.FromContext() = %+v, want subject %q", got, tt.wantSubject)
+			}
+		})
+	}
+}
+
+// A user token claiming admin must not reach admin routes, even though the
+// identity provider signed it.
+func TestAuthRequire_UserTokenWithAdminScope(t *testing.T) {
+	const secret = "0123456789abcdef0123456789abcdef"
+	verifier, err := auth.NewJWTVerifier(config.JWTConfig{HS256Secret: secret, AccountsClaim: "account_ids"})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier() error = %v", err)
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":         "mallory",
+		"exp":         time.Now().Add(time.Hour).Unix(),
+		"scope":       "admin accounts:read",
+		"account_ids": []interface{}{7},
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	authn := NewAuth(fakeAuthenticator{}, verifier)
+	for _, tt := range []struct {
+		scope      string
+		wantStatus int
+	}{
+		{models.ScopeAdmin, http.StatusForbidden},
+		{models.ScopeAccountsWrite, http.StatusForbidden},
+		{models.ScopeAccountsRead, http.StatusOK},
+	} {
+		t.Run(tt.scope, func(t *testing.T) {
+			handler := authn.Require(tt.scope)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			req := httptest.NewRequest(http.MethodGet, "/admin/reports/trial-balance", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
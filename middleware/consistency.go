@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"triplea-backend-assignment/database"
+)
+
+// Consistency reads the consistency query parameter. "strong" sends the
+// request's reads to the primary instead of a replica; "eventual", the
+// default, allows replica reads.
+func Consistency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("consistency") {
+		case "", "eventual":
+		case "strong":
+			r = r.WithContext(database.WithStrongConsistency(r.Context()))
+		default:
+			http.Error(w, "consistency must be strong or eventual", http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
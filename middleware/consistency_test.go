@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"triplea-backend-assignment/database"
+)
+
+func TestConsistency(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantStrong bool
+	}{
+		{name: "default", target: "/accounts/1", wantStatus: http.StatusOK},
+		{name: "eventual", target: "/accounts/1?consistency=eventual", wantStatus: http.StatusOK},
+		{name: "strong", target: "/accounts/1?consistency=strong", wantStatus: http.StatusOK, wantStrong: true},
+		{name: "invalid", target: "/accounts/1?consistency=linearizable", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotStrong bool
+			handler := Consistency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotStrong = database.StrongConsistency(r.Context())
+			}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if gotStrong != tt.wantStrong {
+				t.Errorf("StrongConsistency() = %v, want %v", gotStrong, tt.wantStrong)
+			}
+		})
+	}
+}
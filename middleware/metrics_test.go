@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type observedRequest struct {
+	route  string
+	method string
+	status int
+}
+
+type fakeObserver struct {
+	requests []observedRequest
+}
+
+func (o *fakeObserver) ObserveRequest(route, method string, status int, duration time.Duration) {
+	o.requests = append(o.requests, observedRequest{route: route, method: method, status: status})
+}
+
+func TestMetrics(t *testing.T) {
+	observer := &fakeObserver{}
+	router := mux.NewRouter()
+	router.Use(Metrics(observer))
+	router.HandleFunc("/accounts/{account_id}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["account_id"] == "404" {
+			http.Error(w, "Account not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("{}"))
+	}).Methods("GET")
+
+	for _, path := range []string{"/accounts/1", "/accounts/404"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	want := []observedRequest{
+		{route: "/accounts/{account_id}", method: http.MethodGet, status: http.StatusOK},
+		{route: "/accounts/{account_id}", method: http.MethodGet, status: http.StatusNotFound},
+	}
+	if len(observer.requests) != len(want) {
+		t.Fatalf("observed %+v, want %+v", observer.requests, want)
+	}
+	for i := range want {
+		if observer.requests[i] != want[i] {
+			t.Errorf("request %d observed as %+v, want %+v", i, observer.requests[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"sync"
+
+	"triplea-backend-assignment/auth"
+	"triplea-backend-assignment/config"
+	"triplea-backend-assignment/logging"
+	"triplea-backend-assignment/ratelimit"
+)
+
+// RateLimit gives every client a token bucket per route. Clients are told
+// apart by their principal, so an API key shares one bucket between all its
+// callers, and by IP address when there is no principal. Every IP address
+// also has one bucket for all routes, which applies before authentication.
+// Responses carry RateLimit-* headers; a request over the limit gets 429 Too
+// Many Requests with Retry-After.
+type RateLimit struct {
+	cfg   config.RateLimitConfig
+	perIP *ratelimit.Limiter // nil if unlimited
+
+	mu       sync.Mutex
+	limiters map[string]*ratelimit.Limiter // by route, nil if unlimited
+}
+
+func NewRateLimit(cfg config.RateLimitConfig) *RateLimit {
+	l := &RateLimit{
+		cfg:      cfg,
+		limiters: map[string]*ratelimit.Limiter{},
+	}
+	if cfg.PerIP.Enabled() {
+		l.perIP = ratelimit.NewLimiter(cfg.PerIP)
+	}
+	return l
+}
+
+// LimitIP applies the per-IP limit. Put it in front of Auth.Require and
+// SignatureVerifier.Verify, so that requests with bad credentials are
+// limited before they cost a key lookup or a signature check.
+func (l *RateLimit) LimitIP(next http.Handler) http.Handler {
+	if l.perIP == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.serve(w, r, next, l.perIP, "ip:"+clientIP(r), "per IP")
+	})
+}
+
+// Limit applies the limit of the request's route. Put it after Auth.Require
+// so that the principal is known.
+func (l *RateLimit) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.Method + " " + routeTemplate(r)
+		limiter := l.limiter(route)
+		if limiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		l.serve(w, r, next, limiter, clientKey(r), route)
+	})
+}
+
+// serve passes r on to next if limiter allows key, or answers 429. The
+// headers of an inner limit replace those of an outer one.
+func (l *RateLimit) serve(w http.ResponseWriter, r *http.Request, next http.Handler, limiter *ratelimit.Limiter, key, limit string) {
+	decision := limiter.Allow(key)
+	decision.SetHeaders(w.Header())
+	if !decision.Allowed {
+		ctx := r.Context()
+		logging.FromContext(ctx).InfoContext(ctx, "rate limit exceeded", "limit", limit)
+		http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+	next.ServeHTTP(w, r)
+}
+
+func (l *RateLimit) limiter(route string) *ratelimit.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limiter, ok := l.limiters[route]
+	if ok {
+		return limiter
+	}
+	limit, ok := l.cfg.Routes[route]
+	if !ok {
+		limit = l.cfg.Default
+	}
+	if limit.Enabled() {
+		limiter = ratelimit.NewLimiter(limit)
+	}
+	l.limiters[route] = limiter
+	return limiter
+}
+
+// clientKey includes the principal's kind, so that a user cannot share the
+// bucket of an API key by taking its subject.
+func clientKey(r *http.Request) string {
+	if principal := auth.FromContext(r.Context()); principal != nil {
+		return principal.Kind + ":" + principal.Subject
+	}
+	return "ip:" + clientIP(r)
+}
+
+// clientIP is the address of the connection; X-Forwarded-For is not
+// trusted.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
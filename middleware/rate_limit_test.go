@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"triplea-backend-assignment/auth"
+	"triplea-backend-assignment/config"
+	"triplea-backend-assignment/models"
+)
+
+func TestRateLimit(t *testing.T) {
+	limits := NewRateLimit(config.RateLimitConfig{
+		Default: config.RateLimit{Requests: 2, Per: time.Minute},
+		Routes: map[string]config.RateLimit{
+			"POST /transactions": {Requests: 1, Per: time.Minute},
+			"GET /unlimited":     {},
+		},
+	})
+	router := mux.NewRouter()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	router.Handle("/accounts/{account_id}", limits.Limit(ok)).Methods("GET")
+	router.Handle("/transactions", limits.Limit(ok)).Methods("POST")
+	router.Handle("/unlimited", limits.Limit(ok)).Methods("GET")
+
+	alice := &auth.Principal{Kind: auth.KindUser, Subject: "alice"}
+	bob := &auth.Principal{Kind: auth.KindUser, Subject: "bob"}
+	steps := []struct {
+		name          string
+		method, path  string
+		principal     *auth.Principal
+		remoteAddr    string
+		wantStatus    int
+		wantRemaining string
+	}{
+		{name: "default limit", method: "GET", path: "/accounts/1", principal: alice, wantStatus: http.StatusOK, wantRemaining: "1"},
+		{name: "same route template", method: "GET", path: "/accounts/2", principal: alice, wantStatus: http.StatusOK, wantRemaining: "0"},
+		{name: "default limit exceeded", method: "GET", path: "/accounts/1", principal: alice, wantStatus: http.StatusTooManyRequests, wantRemaining: "0"},
+		{name: "other principal", method: "GET", path: "/accounts/1", principal: bob, wantStatus: http.StatusOK, wantRemaining: "1"},
+		{name: "route limit", method: "POST", path: "/transactions", principal: alice, wantStatus: http.StatusOK, wantRemaining: "0"},
+		{name: "route limit exceeded", method: "POST", path: "/transactions", principal: alice, wantStatus: http.StatusTooManyRequests, wantRemaining: "0"},
+		{name: "by IP", method: "GET", path: "/accounts/1", remoteAddr: "10.0.0.1:5000", wantStatus: http.StatusOK, wantRemaining: "1"},
+		{name: "by IP on another port", method: "GET", path: "/accounts/1", remoteAddr: "10.0.0.1:5001", wantStatus: http.StatusOK, wantRemaining: "0"},
+		{name: "by IP exceeded", method: "GET", path: "/accounts/1", remoteAddr: "10.0.0.1:5002", wantStatus: http.StatusTooManyRequests, wantRemaining: "0"},
+		{name: "unlimited route", method: "GET", path: "/unlimited", principal: alice, wantStatus: http.StatusOK},
+	}
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, nil)
+		if step.principal != nil {
+			req = req.WithContext(auth.WithPrincipal(req.Context(), step.principal))
+		}
+		if step.remoteAddr != "" {
+			req.RemoteAddr = step.remoteAddr
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != step.wantStatus {
+			t.Errorf("%s: status = %d, want %d", step.name, rec.Code, step.wantStatus)
+		}
+		if got := rec.Header().Get("RateLimit-Remaining"); got != step.wantRemaining {
+			t.Errorf("%s: RateLimit-Remaining = %q, want %q", step.name, got, step.wantRemaining)
+		}
+		wantRetryAfter := step.wantStatus == http.StatusTooManyRequests
+		if got := rec.Header().Get("Retry-After") != ""; got != wantRetryAfter {
+			t.Errorf("%s: Retry-After = %q, want it set: %v", step.name, rec.Header().Get("Retry-After"), wantRetryAfter)
+		}
+	}
+}
+
+func TestRateLimitIP_LimitsUnauthenticatedRequests(t *testing.T) {
+	limits := NewRateLimit(config.RateLimitConfig{
+		Default: config.RateLimit{Requests: 100, Per: time.Minute},
+		PerIP:   config.RateLimit{Requests: 2, Per: time.Minute},
+	})
+	authn := NewAuth(fakeAuthenticator{}, nil)
+	handler := limits.LimitIP(authn.Require(models.ScopeAccountsRead)(limits.Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))))
+
+	steps := []struct {
+		name       string
+		remoteAddr string
+		wantStatus int
+	}{
+		{name: "bad key", remoteAddr: "10.0.0.1:5000", wantStatus: http.StatusUnauthorized},
+		{name: "another bad key", remoteAddr: "10.0.0.1:5001", wantStatus: http.StatusUnauthorized},
+		{name: "over the IP limit", remoteAddr: "10.0.0.1:5002", wantStatus: http.StatusTooManyRequests},
+		{name: "other IP", remoteAddr: "10.0.0.2:5000", wantStatus: http.StatusUnauthorized},
+	}
+	for i, step := range steps {
+		req := httptest.NewRequest(http.MethodGet, "/accounts/1", nil)
+		req.RemoteAddr = step.remoteAddr
+		req.Header.Set(APIKeyHeader, "guess-"+strconv.Itoa(i))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != step.wantStatus {
+			t.Errorf("%s: status = %d, want %d", step.name, rec.Code, step.wantStatus)
+		}
+		if step.wantStatus == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+			t.Errorf("%s: 429 without Retry-After", step.name)
+		}
+	}
+}
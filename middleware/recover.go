@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"triplea-backend-assignment/logging"
+	"triplea-backend-assignment/models"
+)
+
+// PanicRecorder records recovered panics. *metrics.Metrics implements it.
+type PanicRecorder interface {
+	RecordPanic(route, method string)
+}
+
+// Recover turns a panic in a handler into a 500 problem response, and logs
+// it with its stack and the request ID. Register it with Router.Use after
+// the logging and metrics middleware, so they see the 500.
+//
+// If the handler had already started its response, the status can no longer
+// be changed; the connection is aborted instead so that the client does not
+// take a truncated response for a complete one.
+func Recover(recorder PanicRecorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped := &responseWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+			}
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered == http.ErrAbortHandler {
+					// The handler aborted on purpose; net/http handles it.
+					panic(recovered)
+				}
+
+				route := routeTemplate(r)
+				recorder.RecordPanic(route, r.Method)
+				logging.FromContext(r.Context()).ErrorContext(r.Context(), "panic recovered",
+					"panic", fmt.Sprint(recovered),
+					"route", route,
+					"method", r.Method,
+					"stack", string(debug.Stack()),
+				)
+
+				if wrapped.wroteHeader {
+					panic(http.ErrAbortHandler)
+				}
+				writePanicProblem(w, r)
+			}()
+
+			next.ServeHTTP(wrapped, r)
+		})
+	}
+}
+
+func writePanicProblem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", models.ProblemContentType)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(models.Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(http.StatusInternalServerError),
+		Status:    http.StatusInternalServerError,
+		Detail:    "The server failed to process the request.",
+		Instance:  r.URL.Path,
+		RequestID: logging.RequestID(r.Context()),
+	})
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"triplea-backend-assignment/logging"
+	"triplea-backend-assignment/models"
+)
+
+type fakePanicRecorder struct {
+	panics []string
+}
+
+func (r *fakePanicRecorder) RecordPanic(route, method string) {
+	r.panics = append(r.panics, method+" "+route)
+}
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		wantPanics  int
+		wantStatus  int
+		wantAborted bool
+	}{
+		{name: "no panic", path: "/accounts/1", wantStatus: http.StatusOK},
+		{name: "panic", path: "/accounts/panic", wantPanics: 1, wantStatus: http.StatusInternalServerError},
+		{name: "panic after response started", path: "/accounts/partial", wantPanics: 1, wantAborted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			base := logging.WithLogger(context.Background(), logging.New(&logs, slog.LevelInfo))
+			recorder := &fakePanicRecorder{}
+			router := mux.NewRouter()
+			router.Use(RequestID, Recover(recorder))
+			router.HandleFunc("/accounts/{account_id}", func(w http.ResponseWriter, r *http.Request) {
+				switch mux.Vars(r)["account_id"] {
+				case "panic":
+					var account *models.Account
+					w.Write([]byte(account.Balance))
+				case "partial":
+					w.Write([]byte("{"))
+					panic("encoder failed")
+				default:
+					w.Write([]byte("{}"))
+				}
+			}).Methods("GET")
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil).WithContext(base)
+			req.Header.Set(RequestIDHeader, "req-1")
+			rec := httptest.NewRecorder()
+			aborted := func() (aborted bool) {
+				defer func() {
+					if recovered := recover(); recovered != nil {
+						if recovered != http.ErrAbortHandler {
+							t.Errorf("panic = %v, want %v", recovered, http.ErrAbortHandler)
+						}
+						aborted = true
+					}
+				}()
+				router.ServeHTTP(rec, req)
+				return false
+			}()
+
+			if aborted != tt.wantAborted {
+				t.Fatalf("aborted = %v, want %v", aborted, tt.wantAborted)
+			}
+			if !tt.wantAborted && rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if len(recorder.panics) != tt.wantPanics {
+				t.Errorf("recorded panics %v, want %d", recorder.panics, tt.wantPanics)
+			}
+			if tt.wantPanics == 0 {
+				return
+			}
+			for _, want := range []string{`"msg":"panic recovered"`, `"request_id":"req-1"`, `"route":"/accounts/{account_id}"`, `"stack":"goroutine `} {
+				if !strings.Contains(logs.String(), want) {
+					t.Errorf("log %s is missing %s", logs.String(), want)
+				}
+			}
+			if tt.wantAborted {
+				return
+			}
+
+			if got := rec.Header().Get("Content-Type"); got != models.ProblemContentType {
+				t.Errorf("Content-Type = %q, want %q", got, models.ProblemContentType)
+			}
+			var problem models.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("body is not JSON: %v: %s", err, rec.Body.String())
+			}
+			want := models.Problem{
+				Type:      "about:blank",
+				Title:     "Internal Server Error",
+				Status:    http.StatusInternalServerError,
+				Detail:    "The server failed to process the request.",
+				Instance:  "/accounts/panic",
+				RequestID: "req-1",
+			}
+			if problem != want {
+				t.Errorf("problem = %+v, want %+v", problem, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"triplea-backend-assignment/logging"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantKeep bool
+	}{
+		{name: "generated when missing", header: ""},
+		{name: "client ID kept", header: "3f2b9c1e-req.42", wantKeep: true},
+		{name: "unsafe characters replaced", header: "abc\ninjected"},
+		{name: "too long replaced", header: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = logging.RequestID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/accounts/1", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			got := rec.Header().Get(RequestIDHeader)
+			if got == "" || got != seen {
+				t.Fatalf("response ID = %q, context ID = %q, want the same non-empty ID", got, seen)
+			}
+			if (got == tt.header) != tt.wantKeep {
+				t.Errorf("request ID = %q, client sent %q, want kept %v", got, tt.header, tt.wantKeep)
+			}
+		})
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	base := logging.WithLogger(context.Background(), logging.New(&buf, slog.LevelInfo))
+
+	handler := RequestID(LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("LoggingMiddleware hides http.Flusher from handlers")
+		}
+		http.Error(w, "Account not found", http.StatusNotFound)
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/accounts/1", nil).WithContext(base)
+	req.Header.Set(RequestIDHeader, "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("log line is not JSON: %v: %s", err, buf.String())
+	}
+	if record["request_id"] != "req-1" || record["status"] != float64(http.StatusNotFound) || record["uri"] != "/accounts/1" {
+		t.Errorf("log record = %v, want request_id, status and uri", record)
+	}
+}
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"triplea-backend-assignment/auth"
+	"triplea-backend-assignment/models"
+)
+
+type fakeNonceStore map[string]time.Time
+
+func (s fakeNonceStore) Remember(ctx context.Context, clientID, nonce string, expiresAt time.Time) (bool, error) {
+	if nonce == "broken-nonce-0000" {
+		return false, errors.New("connection refused")
+	}
+	key := clientID + "/" + nonce
+	if _, ok := s[key]; ok {
+		return false, nil
+	}
+	s[key] = expiresAt
+	return true, nil
+}
+
+func TestSignatureVerify(t *testing.T) {
+	secret := strings.Repeat("s", 32)
+	now := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+	body := `{"source_account_id":1,"destination_account_id":2,"amount":"10.00"}`
+
+	type signedRequest struct {
+		client    string
+		secret    string
+		timestamp time.Time
+		nonce     string
+		body      string
+		sentBody  string
+	}
+	valid := signedRequest{client: "gateway", secret: secret, timestamp: now, nonce: "0123456789abcdef", body: body}
+
+	tests := []struct {
+		name       string
+		modify     func(*signedRequest)
+		unsigned   bool
+		wantStatus int
+	}{
+		{name: "valid", modify: func(*signedRequest) {}, wantStatus: http.StatusOK},
+		{name: "unsigned passes through", unsigned: true, wantStatus: http.StatusOK},
+		{name: "tampered body", modify: func(s *signedRequest) { s.sentBody = strings.Replace(body, "10.00", "1000.00", 1) }, wantStatus: http.StatusUnauthorized},
+		{name: "wrong secret", modify: func(s *signedRequest) { s.secret = strings.Repeat("x", 32) }, wantStatus: http.StatusUnauthorized},
+		{name: "unknown client", modify: func(s *signedRequest) { s.client = "intruder" }, wantStatus: http.StatusUnauthorized},
+		{name: "timestamp within skew", modify: func(s *signedRequest) { s.timestamp = now.Add(-4 * time.Minute) }, wantStatus: http.StatusOK},
+		{name: "stale timestamp", modify: func(s *signedRequest) { s.timestamp = now.Add(-6 * time.Minute) }, wantStatus: http.StatusUnauthorized},
+		{name: "future timestamp", modify: func(s *signedRequest) { s.timestamp = now.Add(6 * time.Minute) }, wantStatus: http.StatusUnauthorized},
+		{name: "short nonce", modify: func(s *signedRequest) { s.nonce = "abc" }, wantStatus: http.StatusUnauthorized},
+		{name: "nonce store fails", modify: func(s *signedRequest) { s.nonce = "broken-nonce-0000" }, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewSignatureVerifier(map[string]string{"gateway": secret}, 5*time.Minute, fakeNonceStore{})
+			verifier.now = func() time.Time { return now }
+
+			var gotPrincipal *auth.Principal
+			var gotBody string
+			handler := verifier.Verify(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPrincipal = auth.FromContext(r.Context())
+				data, _ := io.ReadAll(r.Body)
+				gotBody = string(data)
+			}))
+
+			signed := valid
+			var req *http.Request
+			if tt.unsigned {
+				req = httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader(body))
+			} else {
+				tt.modify(&signed)
+				req = newSignedRequest(signed.client, signed.secret, signed.timestamp, signed.nonce, signed.body, signed.sentBody)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if gotBody != body {
+				t.Errorf("handler read body %q, want %q", gotBody, body)
+			}
+			if tt.unsigned {
+				if gotPrincipal != nil {
+					t.Errorf("auth.FromContext() = %+v, want none for an unsigned request", gotPrincipal)
+				}
+				return
+			}
+			if gotPrincipal == nil || gotPrincipal.Subject != "hmac:gateway" || !gotPrincipal.HasScope(models.ScopeTransfersWrite) {
+				t.Errorf("auth.FromContext() = %+v, want hmac:gateway with %s", gotPrincipal, models.ScopeTransfersWrite)
+			}
+		})
+	}
+}
+
+func TestSignatureVerify_RejectsReplay(t *testing.T) {
+	secret := strings.Repeat("s", 32)
+	now := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+	nonces := fakeNonceStore{}
+	verifier := NewSignatureVerifier(map[string]string{"gateway": secret}, 5*time.Minute, nonces)
+	verifier.now = func() time.Time { return now }
+	handler := verifier.Verify(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for i, want := range []int{http.StatusOK, http.StatusUnauthorized} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newSignedRequest("gateway", secret, now, "0123456789abcdef", "{}", ""))
+		if rec.Code != want {
+			t.Errorf("request #%d status = %d, want %d", i+1, rec.Code, want)
+		}
+	}
+	if expiresAt := nonces["gateway/0123456789abcdef"]; !expiresAt.Equal(now.Add(5 * time.Minute)) {
+		t.Errorf("nonce expires at %v, want %v", expiresAt, now.Add(5*time.Minute))
+	}
+
+	// A forged request must not use up the nonce of a genuine one.
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newSignedRequest("gateway", strings.Repeat("x", 32), now, "fedcba9876543210", "{}", ""))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("forged request status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if _, ok := nonces["gateway/fedcba9876543210"]; ok {
+		t.Error("forged request recorded its nonce")
+	}
+}
+
+// newSignedRequest signs body but sends sentBody, if set, instead.
+func newSignedRequest(client, secret string, timestamp time.Time, nonce, body, sentBody string) *http.Request {
+	if sentBody == "" {
+		sentBody = body
+	}
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader(sentBody))
+	req.Header.Set(SignatureClientHeader, client)
+	req.Header.Set(SignatureTimestampHeader, unix)
+	req.Header.Set(SignatureNonceHeader, nonce)
+	req.Header.Set(SignatureHeader, Signature([]byte(secret), http.MethodPost, "/transactions", unix, nonce, []byte(body)))
+	return req
+}
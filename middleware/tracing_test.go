@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	const parentTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	tests := []struct {
+		name        string
+		path        string
+		traceparent string
+		wantStatus  int
+		wantError   bool
+		wantTraceID string
+	}{
+		{name: "continues incoming trace", path: "/accounts/1", traceparent: "00-" + parentTraceID + "-00f067aa0ba902b7-01",
+			wantStatus: http.StatusOK, wantTraceID: parentTraceID},
+		{name: "starts new trace", path: "/accounts/1", wantStatus: http.StatusOK},
+		{name: "server error", path: "/accounts/500", wantStatus: http.StatusInternalServerError, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+			otel.SetTextMapPropagator(propagation.TraceContext{})
+			defer func() {
+				otel.SetTracerProvider(previousProvider)
+				otel.SetTextMapPropagator(previousPropagator)
+			}()
+
+			router := mux.NewRouter()
+			router.Use(RequestID, Tracing)
+			var handlerSpan trace.SpanContext
+			router.HandleFunc("/accounts/{account_id}", func(w http.ResponseWriter, r *http.Request) {
+				handlerSpan = trace.SpanContextFromContext(r.Context())
+				if mux.Vars(r)["account_id"] == "500" {
+					http.Error(w, "Internal server error", http.StatusInternalServerError)
+					return
+				}
+				w.Write([]byte("{}"))
+			}).Methods("GET")
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			ended := recorder.Ended()
+			if len(ended) != 1 {
+				t.Fatalf("got %d spans, want 1", len(ended))
+			}
+			span := ended[0]
+			if span.Name() != "GET /accounts/{account_id}" {
+				t.Errorf("span name = %q, want %q", span.Name(), "GET /accounts/{account_id}")
+			}
+			if span.SpanKind() != trace.SpanKindServer {
+				t.Errorf("span kind = %v, want %v", span.SpanKind(), trace.SpanKindServer)
+			}
+			if handlerSpan.SpanID() != span.SpanContext().SpanID() {
+				t.Error("handler context does not carry the request span")
+			}
+			if tt.wantTraceID != "" && span.SpanContext().TraceID().String() != tt.wantTraceID {
+				t.Errorf("trace ID = %s, want %s", span.SpanContext().TraceID(), tt.wantTraceID)
+			}
+			if tt.wantTraceID == "" && span.Parent().IsValid() {
+				t.Errorf("span has parent %v, want a new trace", span.Parent())
+			}
+			if got := statusAttribute(span.Attributes()); got != tt.wantStatus {
+				t.Errorf("http.response.status_code = %d, want %d", got, tt.wantStatus)
+			}
+			if failed := span.Status().Code == codes.Error; failed != tt.wantError {
+				t.Errorf("span status = %v, want failed %v", span.Status(), tt.wantError)
+			}
+		})
+	}
+}
+
+func statusAttribute(attrs []attribute.KeyValue) int {
+	for _, attr := range attrs {
+		if attr.Key == "http.response.status_code" {
+			return int(attr.Value.AsInt64())
+		}
+	}
+	return 0
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"time"
 )
 
 type Decimal string
@@ -64,6 +65,10 @@ func (d *Decimal) UnmarshalJSON(data []byte) error {
 type Account struct {
 	AccountID int64   `json:"account_id" db:"account_id"`
 	Balance   Decimal `json:"balance" db:"balance"`
+	// OverdraftLimit is how far below zero the balance may go.
+	OverdraftLimit Decimal   `json:"overdraft_limit" db:"overdraft_limit"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type CreateAccountRequest struct {
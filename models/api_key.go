@@ -0,0 +1,66 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Scopes an API key can be granted. ScopeAdmin grants every other scope as
+// well.
+const (
+	ScopeAccountsRead   = "accounts:read"
+	ScopeAccountsWrite  = "accounts:write"
+	ScopeTransfersWrite = "transfers:write"
+	ScopeAdmin          = "admin"
+)
+
+var knownScopes = map[string]bool{
+	ScopeAccountsRead:   true,
+	ScopeAccountsWrite:  true,
+	ScopeTransfersWrite: true,
+	ScopeAdmin:          true,
+}
+
+const maxAPIKeyNameLength = 100
+
+type APIKey struct {
+	ID         int64      `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// CreatedAPIKey is returned when a key is created. Key is the secret itself,
+// which is not stored and cannot be shown again.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+func (r *CreateAPIKeyRequest) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(r.Name) > maxAPIKeyNameLength {
+		return fmt.Errorf("name must be at most %d characters", maxAPIKeyNameLength)
+	}
+	if len(r.Scopes) == 0 {
+		return errors.New("scopes is required")
+	}
+	for _, scope := range r.Scopes {
+		if !knownScopes[scope] {
+			return fmt.Errorf("scope must be one of %s, %s, %s or %s, got %q",
+				ScopeAccountsRead, ScopeAccountsWrite, ScopeTransfersWrite, ScopeAdmin, scope)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCreateAPIKeyRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     CreateAPIKeyRequest
+		wantErr bool
+	}{
+		{
+			name:    "valid request",
+			req:     CreateAPIKeyRequest{Name: "payments-gateway", Scopes: []string{ScopeAccountsRead, ScopeTransfersWrite}},
+			wantErr: false,
+		},
+		{
+			name:    "missing name",
+			req:     CreateAPIKeyRequest{Scopes: []string{ScopeAccountsRead}},
+			wantErr: true,
+		},
+		{
+			name:    "name too long",
+			req:     CreateAPIKeyRequest{Name: strings.Repeat("a", 101), Scopes: []string{ScopeAccountsRead}},
+			wantErr: true,
+		},
+		{
+			name:    "missing scopes",
+			req:     CreateAPIKeyRequest{Name: "reporting"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown scope",
+			req:     CreateAPIKeyRequest{Name: "reporting", Scopes: []string{ScopeAccountsRead, "accounts:delete"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
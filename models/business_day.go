@@ -0,0 +1,50 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const BusinessDateLayout = "2006-01-02"
+
+type BusinessDay struct {
+	BusinessDate  string    `json:"business_date" db:"business_date"`
+	HighWaterMark int64     `json:"high_water_mark" db:"high_water_mark"`
+	AccountCount  int64     `json:"account_count" db:"account_count"`
+	TotalBalance  Decimal   `json:"total_balance" db:"total_balance"`
+	ClosedAt      time.Time `json:"closed_at" db:"closed_at"`
+}
+
+type BalanceSnapshot struct {
+	BusinessDate   string  `json:"business_date" db:"business_date"`
+	AccountID      int64   `json:"account_id" db:"account_id"`
+	ClosingBalance Decimal `json:"closing_balance" db:"closing_balance"`
+}
+
+type BusinessDaySnapshots struct {
+	BusinessDay *BusinessDay      `json:"business_day"`
+	Snapshots   []BalanceSnapshot `json:"snapshots"`
+}
+
+type CloseBusinessDayRequest struct {
+	BusinessDate string `json:"business_date"`
+}
+
+func (r *CloseBusinessDayRequest) Validate() error {
+	if r.BusinessDate == "" {
+		return errors.New("business_date is required")
+	}
+	if _, err := ParseBusinessDate(r.BusinessDate); err != nil {
+		return err
+	}
+	return nil
+}
+
+func ParseBusinessDate(value string) (time.Time, error) {
+	date, err := time.Parse(BusinessDateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("business_date must be a date in YYYY-MM-DD format: %w", err)
+	}
+	return date, nil
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestCloseBusinessDayRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     CloseBusinessDayRequest
+		wantErr bool
+	}{
+		{
+			name:    "valid request",
+			req:     CloseBusinessDayRequest{BusinessDate: "2024-01-31"},
+			wantErr: false,
+		},
+		{
+			name:    "missing business_date",
+			req:     CloseBusinessDayRequest{BusinessDate: ""},
+			wantErr: true,
+		},
+		{
+			name:    "invalid business_date (wrong format)",
+			req:     CloseBusinessDayRequest{BusinessDate: "31/01/2024"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid business_date (impossible date)",
+			req:     CloseBusinessDayRequest{BusinessDate: "2024-02-30"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Monthly partitions of the transactions table are named
+// PartitionNamePrefix followed by the month in PartitionNameLayout.
+const (
+	PartitionNamePrefix = "transactions_p"
+	PartitionNameLayout = "200601"
+)
+
+// TransactionPartition is one month of the transactions table, holding the
+// transactions created in [Start, End).
+type TransactionPartition struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+// MonthPartition returns the partition for the UTC month of t.
+func MonthPartition(t time.Time) TransactionPartition {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return TransactionPartition{
+		Name:  PartitionNamePrefix + start.Format(PartitionNameLayout),
+		Start: start,
+		End:   start.AddDate(0, 1, 0),
+	}
+}
+
+type ArchivedPartition struct {
+	Name string
+	Rows int64
+	File string
+}
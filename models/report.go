@@ -0,0 +1,96 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const DefaultPendingThreshold = 5 * time.Minute
+
+type TrialBalanceRequest struct {
+	From             string
+	To               string
+	PendingThreshold string
+}
+
+func (r *TrialBalanceRequest) Validate() error {
+	var from, to time.Time
+	var err error
+	if r.From != "" {
+		if from, err = time.Parse(BusinessDateLayout, r.From); err != nil {
+			return fmt.Errorf("from must be a date in YYYY-MM-DD format: %w", err)
+		}
+	}
+	if r.To != "" {
+		if to, err = time.Parse(BusinessDateLayout, r.To); err != nil {
+			return fmt.Errorf("to must be a date in YYYY-MM-DD format: %w", err)
+		}
+	}
+	if r.From != "" && r.To != "" && to.Before(from) {
+		return errors.New("to cannot be before from")
+	}
+	if r.PendingThreshold != "" {
+		threshold, err := time.ParseDuration(r.PendingThreshold)
+		if err != nil {
+			return fmt.Errorf("pending_threshold must be a duration such as 15m: %w", err)
+		}
+		if threshold <= 0 {
+			return errors.New("pending_threshold must be greater than zero")
+		}
+	}
+	return nil
+}
+
+func (r *TrialBalanceRequest) Threshold() time.Duration {
+	threshold, err := time.ParseDuration(r.PendingThreshold)
+	if err != nil || threshold <= 0 {
+		return DefaultPendingThreshold
+	}
+	return threshold
+}
+
+type TrialBalanceReport struct {
+	GeneratedAt  time.Time                 `json:"generated_at"`
+	From         string                    `json:"from,omitempty"`
+	To           string                    `json:"to,omitempty"`
+	Balanced     bool                      `json:"balanced"`
+	Accounts     AccountTotals             `json:"accounts"`
+	Transactions []TransactionStatusTotals `json:"transactions"`
+	Ledger       LedgerCheck               `json:"ledger"`
+	StuckPending StuckPendingTransactions  `json:"stuck_pending"`
+}
+
+type AccountTotals struct {
+	Count               int64   `json:"count"`
+	TotalBalance        Decimal `json:"total_balance"`
+	TotalInitialBalance Decimal `json:"total_initial_balance"`
+	Balanced            bool    `json:"balanced"`
+}
+
+type TransactionStatusTotals struct {
+	Status      string  `json:"status"`
+	Count       int64   `json:"count"`
+	TotalAmount Decimal `json:"total_amount"`
+}
+
+type LedgerCheck struct {
+	TransferredTotal           Decimal              `json:"transferred_total"`
+	MismatchedTransactionCount int64                `json:"mismatched_transaction_count"`
+	MismatchedTransactions     []int64              `json:"mismatched_transactions"`
+	DiscrepancyCount           int64                `json:"discrepancy_count"`
+	Discrepancies              []AccountDiscrepancy `json:"discrepancies"`
+	Balanced                   bool                 `json:"balanced"`
+}
+
+type AccountDiscrepancy struct {
+	AccountID       int64   `json:"account_id"`
+	Balance         Decimal `json:"balance"`
+	ExpectedBalance Decimal `json:"expected_balance"`
+}
+
+type StuckPendingTransactions struct {
+	Threshold    string        `json:"threshold"`
+	Count        int64         `json:"count"`
+	Transactions []Transaction `json:"transactions"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrialBalanceRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     TrialBalanceRequest
+		wantErr bool
+	}{
+		{
+			name:    "empty request covers all time",
+			req:     TrialBalanceRequest{},
+			wantErr: false,
+		},
+		{
+			name:    "valid window and threshold",
+			req:     TrialBalanceRequest{From: "2024-01-01", To: "2024-01-31", PendingThreshold: "15m"},
+			wantErr: false,
+		},
+		{
+			name:    "invalid from",
+			req:     TrialBalanceRequest{From: "yesterday"},
+			wantErr: true,
+		},
+		{
+			name:    "to before from",
+			req:     TrialBalanceRequest{From: "2024-02-01", To: "2024-01-01"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid pending_threshold",
+			req:     TrialBalanceRequest{PendingThreshold: "soon"},
+			wantErr: true,
+		},
+		{
+			name:    "non-positive pending_threshold",
+			req:     TrialBalanceRequest{PendingThreshold: "0s"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTrialBalanceRequest_Threshold(t *testing.T) {
+	if got := (&TrialBalanceRequest{}).Threshold(); got != DefaultPendingThreshold {
+		t.Errorf("Threshold() = %v, want default %v", got, DefaultPendingThreshold)
+	}
+	if got := (&TrialBalanceRequest{PendingThreshold: "90s"}).Threshold(); got != 90*time.Second {
+		t.Errorf("Threshold() = %v, want 90s", got)
+	}
+}
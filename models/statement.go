@@ -0,0 +1,60 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	StatementFormatCSV  = "csv"
+	StatementFormatJSON = "json"
+)
+
+const (
+	StatementDirectionDebit  = "debit"
+	StatementDirectionCredit = "credit"
+)
+
+type StatementLine struct {
+	TransactionID         int64     `json:"transaction_id" db:"id"`
+	CreatedAt             time.Time `json:"created_at" db:"created_at"`
+	CounterpartyAccountID int64     `json:"counterparty_account_id" db:"counterparty_account_id"`
+	Direction             string    `json:"direction" db:"direction"`
+	Amount                Decimal   `json:"amount" db:"amount"`
+	RunningBalance        Decimal   `json:"running_balance" db:"running_balance"`
+}
+
+type StatementRequest struct {
+	AccountID int64
+	From      string
+	To        string
+	Format    string
+}
+
+func (r *StatementRequest) Validate() error {
+	if r.AccountID <= 0 {
+		return errors.New("account_id must be a positive integer")
+	}
+	if r.From == "" {
+		return errors.New("from is required")
+	}
+	if r.To == "" {
+		return errors.New("to is required")
+	}
+	from, err := time.Parse(BusinessDateLayout, r.From)
+	if err != nil {
+		return fmt.Errorf("from must be a date in YYYY-MM-DD format: %w", err)
+	}
+	to, err := time.Parse(BusinessDateLayout, r.To)
+	if err != nil {
+		return fmt.Errorf("to must be a date in YYYY-MM-DD format: %w", err)
+	}
+	if to.Before(from) {
+		return errors.New("to cannot be before from")
+	}
+	if r.Format != StatementFormatCSV && r.Format != StatementFormatJSON {
+		return errors.New("format must be one of csv, json")
+	}
+	return nil
+}
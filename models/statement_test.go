@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestStatementRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     StatementRequest
+		wantErr bool
+	}{
+		{
+			name:    "valid json request",
+			req:     StatementRequest{AccountID: 123, From: "2024-01-01", To: "2024-01-31", Format: "json"},
+			wantErr: false,
+		},
+		{
+			name:    "valid csv request for a single day",
+			req:     StatementRequest{AccountID: 123, From: "2024-01-01", To: "2024-01-01", Format: "csv"},
+			wantErr: false,
+		},
+		{
+			name:    "invalid account_id (zero)",
+			req:     StatementRequest{AccountID: 0, From: "2024-01-01", To: "2024-01-31", Format: "json"},
+			wantErr: true,
+		},
+		{
+			name:    "missing from",
+			req:     StatementRequest{AccountID: 123, From: "", To: "2024-01-31", Format: "json"},
+			wantErr: true,
+		},
+		{
+			name:    "missing to",
+			req:     StatementRequest{AccountID: 123, From: "2024-01-01", To: "", Format: "json"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid from (wrong format)",
+			req:     StatementRequest{AccountID: 123, From: "01/01/2024", To: "2024-01-31", Format: "json"},
+			wantErr: true,
+		},
+		{
+			name:    "to before from",
+			req:     StatementRequest{AccountID: 123, From: "2024-02-01", To: "2024-01-31", Format: "json"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported format",
+			req:     StatementRequest{AccountID: 123, From: "2024-01-01", To: "2024-01-31", Format: "xml"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -13,6 +13,8 @@ type Transaction struct {
 	DestinationAccountID int64   `json:"destination_account_id" db:"destination_account_id"`
 	Amount             Decimal   `json:"amount" db:"amount"`
 	Status             string    `json:"status" db:"status"`
+	FailureReason      string    `json:"failure_reason,omitempty" db:"failure_reason"`
+	BalanceAtDecision  Decimal   `json:"balance_at_decision,omitempty" db:"balance_at_decision"`
 	CreatedAt          time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt          time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -23,6 +25,27 @@ const (
 	TransactionStatusFailed    = "failed"
 )
 
+// TransactionStatuses must match the transaction_status enum in the database.
+var TransactionStatuses = []string{
+	TransactionStatusPending,
+	TransactionStatusCompleted,
+	TransactionStatusFailed,
+}
+
+func IsValidTransactionStatus(status string) bool {
+	for _, s := range TransactionStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	FailureReasonInsufficientFunds = "insufficient_funds"
+	FailureReasonNotApplied        = "not_applied"
+)
+
 type CreateTransactionRequest struct {
 	SourceAccountID      int64  `json:"source_account_id"`
 	DestinationAccountID int64  `json:"destination_account_id"`
@@ -52,3 +75,35 @@ func (r *CreateTransactionRequest) Validate() error {
 	return nil
 }
 
+const (
+	DefaultTransactionHistoryLimit = 50
+	MaxTransactionHistoryLimit     = 200
+)
+
+type TransactionHistoryRequest struct {
+	AccountID int64
+	Status    string
+	BeforeID  int64
+	Limit     int
+}
+
+func (r *TransactionHistoryRequest) Validate() error {
+	if r.AccountID <= 0 {
+		return errors.New("account_id must be a positive integer")
+	}
+	if r.Status != "" && !IsValidTransactionStatus(r.Status) {
+		return errors.New("status must be one of pending, completed, failed")
+	}
+	if r.BeforeID < 0 {
+		return errors.New("before_id must be a positive integer")
+	}
+	if r.Limit < 0 || r.Limit > MaxTransactionHistoryLimit {
+		return fmt.Errorf("limit must be between 1 and %d", MaxTransactionHistoryLimit)
+	}
+	return nil
+}
+
+type TransactionHistory struct {
+	Transactions []Transaction `json:"transactions"`
+	NextBeforeID int64         `json:"next_before_id,omitempty"`
+}
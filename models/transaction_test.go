@@ -94,3 +94,51 @@ func TestCreateTransactionRequest_Validate(t *testing.T) {
 	}
 }
 
+
+func TestTransactionHistoryRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     TransactionHistoryRequest
+		wantErr bool
+	}{
+		{
+			name:    "valid request with defaults",
+			req:     TransactionHistoryRequest{AccountID: 123},
+			wantErr: false,
+		},
+		{
+			name:    "valid request filtered by failed status",
+			req:     TransactionHistoryRequest{AccountID: 123, Status: "failed", BeforeID: 10, Limit: 20},
+			wantErr: false,
+		},
+		{
+			name:    "invalid account_id (zero)",
+			req:     TransactionHistoryRequest{AccountID: 0},
+			wantErr: true,
+		},
+		{
+			name:    "unknown status",
+			req:     TransactionHistoryRequest{AccountID: 123, Status: "rejected"},
+			wantErr: true,
+		},
+		{
+			name:    "negative before_id",
+			req:     TransactionHistoryRequest{AccountID: 123, BeforeID: -1},
+			wantErr: true,
+		},
+		{
+			name:    "limit above maximum",
+			req:     TransactionHistoryRequest{AccountID: 123, Limit: MaxTransactionHistoryLimit + 1},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"triplea-backend-assignment/config"
+)
+
+// Limiter keeps a token bucket per key. A bucket holds up to
+// limit.Requests tokens, refills at limit.Requests per limit.Per and starts
+// full; every allowed request takes one token.
+type Limiter struct {
+	limit    config.RateLimit
+	interval time.Duration // to refill one token
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Decision is the outcome of Limiter.Allow.
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request is allowed, if this one
+	// was not.
+	RetryAfter time.Duration
+}
+
+// NewLimiter returns a limiter for limit, which must be enabled.
+func NewLimiter(limit config.RateLimit) *Limiter {
+	return &Limiter{
+		limit:    limit,
+		interval: limit.Per / time.Duration(limit.Requests),
+		buckets:  map[string]*bucket{},
+		now:      time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of key, if it has one.
+func (l *Limiter) Allow(key string) Decision {
+	now := l.now()
+	capacity := float64(l.limit.Requests)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refilled(b, now)
+	b.updated = now
+
+	decision := Decision{Limit: l.limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = l.untilTokens(1 - b.tokens)
+	}
+	decision.Remaining = int(b.tokens)
+	decision.Reset = l.untilTokens(capacity - b.tokens)
+	return decision
+}
+
+func (l *Limiter) refilled(b *bucket, now time.Time) float64 {
+	elapsed := now.Sub(b.updated)
+	if elapsed <= 0 {
+		return b.tokens
+	}
+	return math.Min(float64(l.limit.Requests), b.tokens+float64(elapsed)/float64(l.interval))
+}
+
+func (l *Limiter) untilTokens(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens * float64(l.interval)))
+}
+
+// sweep drops the buckets that have refilled, which behave like new ones,
+// so idle keys do not accumulate. It runs at most once per limit.Per, and
+// at least a minute apart.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < max(l.limit.Per, time.Minute) {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if l.refilled(b, now) >= float64(l.limit.Requests) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// SetHeaders sets the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers of the IETF RateLimit header fields draft and,
+// for a request that was not allowed, Retry-After. Times are in whole
+// seconds, rounded up.
+func (d Decision) SetHeaders(header http.Header) {
+	header.Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	header.Set("RateLimit-Reset", strconv.FormatInt(seconds(d.Reset), 10))
+	if !d.Allowed {
+		header.Set("Retry-After", strconv.FormatInt(max(seconds(d.RetryAfter), 1), 10))
+	}
+}
+
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
+
+// ExceededError is returned for an operation that a Limiter did not allow.
+type ExceededError struct {
+	Decision Decision
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("rate limit exceeded, retry in %s", time.Duration(seconds(e.Decision.RetryAfter))*time.Second)
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"triplea-backend-assignment/config"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	now := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(config.RateLimit{Requests: 3, Per: 3 * time.Second})
+	limiter.now = func() time.Time { return now }
+
+	steps := []struct {
+		name           string
+		advance        time.Duration
+		key            string
+		wantAllowed    bool
+		wantRemaining  int
+		wantRetryAfter time.Duration
+	}{
+		{name: "first request", key: "a", wantAllowed: true, wantRemaining: 2},
+		{name: "second request", key: "a", wantAllowed: true, wantRemaining: 1},
+		{name: "third request", key: "a", wantAllowed: true, wantRemaining: 0},
+		{name: "bucket empty", key: "a", wantAllowed: false, wantRemaining: 0, wantRetryAfter: time.Second},
+		{name: "other key has its own bucket", key: "b", wantAllowed: true, wantRemaining: 2},
+		{name: "half a token refilled", advance: 500 * time.Millisecond, key: "a", wantAllowed: false, wantRetryAfter: 500 * time.Millisecond},
+		{name: "one token refilled", advance: 500 * time.Millisecond, key: "a", wantAllowed: true, wantRemaining: 0},
+		{name: "refill stops at capacity", advance: time.Hour, key: "a", wantAllowed: true, wantRemaining: 2},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		got := limiter.Allow(step.key)
+		if got.Allowed != step.wantAllowed || got.Remaining != step.wantRemaining || got.RetryAfter != step.wantRetryAfter || got.Limit != 3 {
+			t.Errorf("%s: Allow(%s) = %+v, want allowed %v, remaining %d, retry after %v",
+				step.name, step.key, got, step.wantAllowed, step.wantRemaining, step.wantRetryAfter)
+		}
+	}
+}
+
+func TestLimiterSweepsFullBuckets(t *testing.T) {
+	now := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(config.RateLimit{Requests: 10, Per: time.Second})
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("idle")
+	now = now.Add(2 * time.Minute)
+	for i := 0; i < 10; i++ {
+		limiter.Allow("busy")
+	}
+	if _, ok := limiter.buckets["idle"]; ok {
+		t.Error("bucket of an idle key was kept")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if got := limiter.Allow("busy"); !got.Allowed || got.Remaining != 9 {
+		t.Errorf("Allow(busy) after the sweep = %+v, want a full bucket", got)
+	}
+}
+
+func TestDecisionSetHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		decision Decision
+		want     map[string]string
+	}{
+		{
+			name:     "allowed",
+			decision: Decision{Allowed: true, Limit: 10, Remaining: 7, Reset: 2500 * time.Millisecond},
+			want:     map[string]string{"RateLimit-Limit": "10", "RateLimit-Remaining": "7", "RateLimit-Reset": "3", "Retry-After": ""},
+		},
+		{
+			name:     "limited",
+			decision: Decision{Limit: 10, Reset: 10 * time.Second, RetryAfter: 100 * time.Millisecond},
+			want:     map[string]string{"RateLimit-Limit": "10", "RateLimit-Remaining": "0", "RateLimit-Reset": "10", "Retry-After": "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			tt.decision.SetHeaders(header)
+			for name, want := range tt.want {
+				if got := header.Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
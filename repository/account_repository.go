@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
@@ -8,38 +9,78 @@ import (
 	"triplea-backend-assignment/models"
 )
 
-type AccountRepository struct{}
+const accountColumns = `account_id, balance, overdraft_limit, created_at, updated_at`
 
-func NewAccountRepository() *AccountRepository {
-	return &AccountRepository{}
+func scanAccount(row rowScanner, account *models.Account) error {
+	err := row.Scan(&account.AccountID, &account.Balance, &account.OverdraftLimit, &account.CreatedAt, &account.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	account.CreatedAt = account.CreatedAt.UTC()
+	account.UpdatedAt = account.UpdatedAt.UTC()
+	return nil
+}
+
+type PostgresAccountRepository struct {
+	store *database.Store
+
+	create          *database.Statement
+	getByID         *database.Statement
+	getByIDWithLock *database.Statement
+	updateBalance   *database.Statement
+	exists          *database.Statement
+}
+
+// NewPostgresAccountRepository prepares every query of the repository.
+func NewPostgresAccountRepository(ctx context.Context, store *database.Store) (*PostgresAccountRepository, error) {
+	r := &PostgresAccountRepository{store: store}
+	err := prepareStatements(ctx, store, []statementSpec{
+		{&r.create, "AccountRepository.Create",
+			`INSERT INTO accounts (account_id, balance, initial_balance) VALUES ($1, $2, $2)`},
+		{&r.getByID, "AccountRepository.GetByID",
+			`SELECT ` + accountColumns + ` FROM accounts WHERE account_id = $1`},
+		{&r.getByIDWithLock, "AccountRepository.GetByIDWithLock",
+			`SELECT ` + accountColumns + ` FROM accounts WHERE account_id = $1 FOR UPDATE`},
+		{&r.updateBalance, "AccountRepository.UpdateBalance",
+			`UPDATE accounts SET balance = $1, updated_at = CURRENT_TIMESTAMP WHERE account_id = $2`},
+		{&r.exists, "AccountRepository.Exists",
+			`SELECT EXISTS(SELECT 1 FROM accounts WHERE account_id = $1)`},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
-func (r *AccountRepository) Create(accountID int64, balance models.Decimal) error {
-	query := `INSERT INTO accounts (account_id, balance) VALUES ($1, $2)`
-	_, err := database.DB.Exec(query, accountID, balance)
+func (r *PostgresAccountRepository) Create(ctx context.Context, accountID int64, balance models.Decimal) error {
+	_, err := r.create.On(ctx, r.store.DB()).ExecContext(ctx, accountID, balance)
 	if err != nil {
+		if domainErr := constraintError(err); domainErr != nil {
+			return domainErr
+		}
 		return fmt.Errorf("failed to create account: %w", err)
 	}
 	return nil
 }
 
-func (r *AccountRepository) GetByID(accountID int64) (*models.Account, error) {
-	query := `SELECT account_id, balance FROM accounts WHERE account_id = $1`
+func (r *PostgresAccountRepository) GetByID(ctx context.Context, accountID int64) (*models.Account, error) {
 	account := &models.Account{}
-	err := database.DB.QueryRow(query, accountID).Scan(&account.AccountID, &account.Balance)
+	err := scanAccount(r.getByID.On(ctx, r.store.ReadDB(ctx)).QueryRowContext(ctx, accountID), account)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("account not found")
+			return nil, ErrAccountNotFound
 		}
 		return nil, fmt.Errorf("failed to get account: %w", err)
 	}
 	return account, nil
 }
 
-func (r *AccountRepository) UpdateBalance(accountID int64, newBalance models.Decimal) error {
-	query := `UPDATE accounts SET balance = $1, updated_at = CURRENT_TIMESTAMP WHERE account_id = $2`
-	result, err := database.DB.Exec(query, newBalance, accountID)
+func (r *PostgresAccountRepository) UpdateBalance(ctx context.Context, accountID int64, newBalance models.Decimal) error {
+	result, err := r.updateBalance.On(ctx, r.store.DB()).ExecContext(ctx, newBalance, accountID)
 	if err != nil {
+		if domainErr := constraintError(err); domainErr != nil {
+			return domainErr
+		}
 		return fmt.Errorf("failed to update account balance: %w", err)
 	}
 	rowsAffected, err := result.RowsAffected()
@@ -47,31 +88,54 @@ func (r *AccountRepository) UpdateBalance(accountID int64, newBalance models.Dec
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 	if rowsAffected == 0 {
-		return fmt.Errorf("account not found")
+		return ErrAccountNotFound
 	}
 	return nil
 }
 
-func (r *AccountRepository) Exists(accountID int64) (bool, error) {
-	query := `SELECT EXISTS(SELECT 1 FROM accounts WHERE account_id = $1)`
+func (r *PostgresAccountRepository) Exists(ctx context.Context, accountID int64) (bool, error) {
 	var exists bool
-	err := database.DB.QueryRow(query, accountID).Scan(&exists)
+	err := r.exists.On(ctx, r.store.DB()).QueryRowContext(ctx, accountID).Scan(&exists)
 	if err != nil {
 		return false, fmt.Errorf("failed to check account existence: %w", err)
 	}
 	return exists, nil
 }
 
-func (r *AccountRepository) GetByIDWithLock(tx *sql.Tx, accountID int64) (*models.Account, error) {
-	query := `SELECT account_id, balance FROM accounts WHERE account_id = $1 FOR UPDATE`
+func (r *PostgresAccountRepository) GetByIDWithLock(ctx context.Context, tx Tx, accountID int64) (*models.Account, error) {
+	sqlTx, err := unwrapTx(tx)
+	if err != nil {
+		return nil, err
+	}
 	account := &models.Account{}
-	err := tx.QueryRow(query, accountID).Scan(&account.AccountID, &account.Balance)
+	err = scanAccount(r.getByIDWithLock.InTx(ctx, sqlTx).QueryRowContext(ctx, accountID), account)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("account not found")
+			return nil, ErrAccountNotFound
 		}
 		return nil, fmt.Errorf("failed to get account: %w", err)
 	}
 	return account, nil
 }
 
+func (r *PostgresAccountRepository) UpdateBalanceInTx(ctx context.Context, tx Tx, accountID int64, newBalance models.Decimal) error {
+	sqlTx, err := unwrapTx(tx)
+	if err != nil {
+		return err
+	}
+	result, err := r.updateBalance.InTx(ctx, sqlTx).ExecContext(ctx, newBalance, accountID)
+	if err != nil {
+		if domainErr := constraintError(err); domainErr != nil {
+			return domainErr
+		}
+		return fmt.Errorf("failed to update account balance: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"triplea-backend-assignment/database"
+	"triplea-backend-assignment/models"
+)
+
+type PostgresAPIKeyRepository struct {
+	store *database.Store
+
+	create    *database.Statement
+	getByHash *database.Statement
+	list      *database.Statement
+	revoke    *database.Statement
+	touch     *database.Statement
+}
+
+func NewPostgresAPIKeyRepository(ctx context.Context, store *database.Store) (*PostgresAPIKeyRepository, error) {
+	r := &PostgresAPIKeyRepository{store: store}
+	err := prepareStatements(ctx, store, []statementSpec{
+		{&r.create, "APIKeyRepository.Create",
+			`INSERT INTO api_keys (name, prefix, key_hash, scopes) VALUES ($1, $2, $3, $4)
+			 RETURNING id, name, prefix, scopes, created_at, last_used_at, revoked_at`},
+		{&r.getByHash, "APIKeyRepository.GetByHash",
+			`SELECT id, name, prefix, scopes, created_at, last_used_at, revoked_at
+			 FROM api_keys WHERE key_hash = $1`},
+		{&r.list, "APIKeyRepository.List",
+			`SELECT id, name, prefix, scopes, created_at, last_used_at, revoked_at
+			 FROM api_keys ORDER BY id`},
+		{&r.revoke, "APIKeyRepository.Revoke",
+			`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP) WHERE id = $1`},
+		{&r.touch, "APIKeyRepository.TouchLastUsed",
+			`UPDATE api_keys SET last_used_at = $2 WHERE id = $1`},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func scanAPIKey(row rowScanner, key *models.APIKey) error {
+	var lastUsedAt, revokedAt sql.NullTime
+	if err := row.Scan(&key.ID, &key.Name, &key.Prefix, pq.Array(&key.Scopes), &key.CreatedAt, &lastUsedAt, &revokedAt); err != nil {
+		return err
+	}
+	key.CreatedAt = key.CreatedAt.UTC()
+	key.LastUsedAt = utcTimeOrNil(lastUsedAt)
+	key.RevokedAt = utcTimeOrNil(revokedAt)
+	return nil
+}
+
+func utcTimeOrNil(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	utc := t.Time.UTC()
+	return &utc
+}
+
+func (r *PostgresAPIKeyRepository) Create(ctx context.Context, name, prefix, keyHash string, scopes []string) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	err := scanAPIKey(r.create.On(ctx, r.store.DB()).QueryRowContext(ctx, name, prefix, keyHash, pq.Array(scopes)), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+	return key, nil
+}
+
+// GetByHash reads from the primary, so a revoked key stops working at once.
+func (r *PostgresAPIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	err := scanAPIKey(r.getByHash.On(ctx, r.store.DB()).QueryRowContext(ctx, keyHash), key)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return key, nil
+}
+
+func (r *PostgresAPIKeyRepository) List(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := r.list.On(ctx, r.store.DB()).QueryContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		if err := scanAPIKey(rows, &key); err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Revoke is idempotent; revoking a revoked key keeps its revocation time.
+func (r *PostgresAPIKeyRepository) Revoke(ctx context.Context, id int64) error {
+	result, err := r.revoke.On(ctx, r.store.DB()).ExecContext(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (r *PostgresAPIKeyRepository) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	if _, err := r.touch.On(ctx, r.store.DB()).ExecContext(ctx, id, at); err != nil {
+		return fmt.Errorf("failed to record API key use: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"triplea-backend-assignment/database"
+	"triplea-backend-assignment/models"
+)
+
+type BusinessDayRepository struct {
+	store *database.Store
+
+	create          *database.Statement
+	highWaterMark   *database.Statement
+	createSnapshots *database.Statement
+	finalize        *database.Statement
+	getByDate       *database.Statement
+	listSnapshots   *database.Statement
+}
+
+func NewBusinessDayRepository(ctx context.Context, store *database.Store) (*BusinessDayRepository, error) {
+	r := &BusinessDayRepository{store: store}
+	err := prepareStatements(ctx, store, []statementSpec{
+		{&r.create, "BusinessDayRepository.Create",
+			`INSERT INTO business_days (business_date) VALUES ($1) ON CONFLICT (business_date) DO NOTHING`},
+		{&r.highWaterMark, "BusinessDayRepository.HighWaterMark",
+			`SELECT COALESCE(MAX(id), 0) FROM transactions`},
+		{&r.createSnapshots, "BusinessDayRepository.CreateSnapshots",
+			`INSERT INTO account_balance_snapshots (business_date, account_id, closing_balance)
+			 SELECT $1, a.account_id,
+					a.balance
+					- COALESCE((SELECT SUM(t.amount) FROM transactions t
+								WHERE t.destination_account_id = a.account_id AND t.status = $2
+								  AND t.created_at >= ($1::date + 1)::timestamp AT TIME ZONE 'UTC'), 0)
+					+ COALESCE((SELECT SUM(t.amount) FROM transactions t
+								WHERE t.source_account_id = a.account_id AND t.status = $2
+								  AND t.created_at >= ($1::date + 1)::timestamp AT TIME ZONE 'UTC'), 0)
+			 FROM accounts a
+			 WHERE a.created_at < ($1::date + 1)::timestamp AT TIME ZONE 'UTC'`},
+		{&r.finalize, "BusinessDayRepository.Finalize",
+			`UPDATE business_days
+			 SET high_water_mark = $2,
+				 account_count = s.account_count,
+				 total_balance = s.total_balance,
+				 closed_at = CURRENT_TIMESTAMP
+			 FROM (SELECT COUNT(*) AS account_count, COALESCE(SUM(closing_balance), 0) AS total_balance
+				   FROM account_balance_snapshots WHERE business_date = $1) s
+			 WHERE business_date = $1
+			 RETURNING to_char(business_date, 'YYYY-MM-DD'), high_water_mark, business_days.account_count,
+					   business_days.total_balance, closed_at`},
+		{&r.getByDate, "BusinessDayRepository.GetByDate",
+			`SELECT to_char(business_date, 'YYYY-MM-DD'), high_water_mark, account_count, total_balance, closed_at
+			 FROM business_days WHERE business_date = $1`},
+		{&r.listSnapshots, "BusinessDayRepository.ListSnapshots",
+			`SELECT to_char(business_date, 'YYYY-MM-DD'), account_id, closing_balance
+			 FROM account_balance_snapshots WHERE business_date = $1 ORDER BY account_id`},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Close freezes the closing balance of every account for businessDate, a UTC
+// calendar day. Completed transactions created after the day are backed out
+// of the current balances, so a day can be closed after midnight without
+// leaking the next day's activity into it. The high-water mark is the last
+// transaction the close saw: every transaction up to it is reflected in the
+// snapshot, and every later one commits after the close.
+func (r *BusinessDayRepository) Close(ctx context.Context, businessDate string) (*models.BusinessDay, error) {
+	tx, err := r.store.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := r.create.InTx(ctx, tx).ExecContext(ctx, businessDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create business day: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, fmt.Errorf("business day %s is already closed", businessDate)
+	}
+
+	// Waits for in-flight transfers to commit and blocks new ones until the
+	// snapshot is written, so balances and transaction rows agree and no
+	// transaction below the high-water mark can commit later. Transfers
+	// insert their transaction before updating balances, so transactions is
+	// locked first.
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE transactions, accounts IN SHARE MODE`); err != nil {
+		return nil, fmt.Errorf("failed to lock transactions and accounts: %w", err)
+	}
+
+	var highWaterMark int64
+	if err := r.highWaterMark.InTx(ctx, tx).QueryRowContext(ctx).Scan(&highWaterMark); err != nil {
+		return nil, fmt.Errorf("failed to get transaction high-water mark: %w", err)
+	}
+
+	if _, err := r.createSnapshots.InTx(ctx, tx).ExecContext(ctx, businessDate, models.TransactionStatusCompleted); err != nil {
+		return nil, fmt.Errorf("failed to create balance snapshots: %w", err)
+	}
+
+	day := &models.BusinessDay{}
+	err = r.finalize.InTx(ctx, tx).QueryRowContext(ctx, businessDate, highWaterMark).
+		Scan(&day.BusinessDate, &day.HighWaterMark, &day.AccountCount, &day.TotalBalance, &day.ClosedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finalize business day: %w", err)
+	}
+	day.ClosedAt = day.ClosedAt.UTC()
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return day, nil
+}
+
+func (r *BusinessDayRepository) GetByDate(ctx context.Context, businessDate string) (*models.BusinessDay, error) {
+	day := &models.BusinessDay{}
+	err := r.getByDate.On(ctx, r.store.DB()).QueryRowContext(ctx, businessDate).
+		Scan(&day.BusinessDate, &day.HighWaterMark, &day.AccountCount, &day.TotalBalance, &day.ClosedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("business day not found")
+		}
+		return nil, fmt.Errorf("failed to get business day: %w", err)
+	}
+	day.ClosedAt = day.ClosedAt.UTC()
+	return day, nil
+}
+
+func (r *BusinessDayRepository) ListSnapshots(ctx context.Context, businessDate string) ([]models.BalanceSnapshot, error) {
+	rows, err := r.listSnapshots.On(ctx, r.store.DB()).QueryContext(ctx, businessDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list balance snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []models.BalanceSnapshot{}
+	for rows.Next() {
+		var snapshot models.BalanceSnapshot
+		if err := rows.Scan(&snapshot.BusinessDate, &snapshot.AccountID, &snapshot.ClosingBalance); err != nil {
+			return nil, fmt.Errorf("failed to scan balance snapshot: %w", err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list balance snapshots: %w", err)
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,708 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"triplea-backend-assignment/models"
+)
+
+// repositoryFixture is one empty store under test. Every implementation of
+// the repository interfaces must pass runConformance.
+type repositoryFixture struct {
+	txManager    TxManager
+	accounts     AccountRepository
+	transactions TransactionRepository
+}
+
+func runConformance(t *testing.T, newFixture func(t *testing.T) repositoryFixture) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, f repositoryFixture)
+	}{
+		{"AccountCreateAndGet", testAccountCreateAndGet},
+		{"AccountCreateDuplicate", testAccountCreateDuplicate},
+		{"AccountNotFound", testAccountNotFound},
+		{"AccountUpdateBalance", testAccountUpdateBalance},
+		{"TxCommit", testTxCommit},
+		{"TxRollback", testTxRollback},
+		{"TransactionCreateAndStatus", testTransactionCreateAndStatus},
+		{"TransactionCreateFailed", testTransactionCreateFailed},
+		{"TransactionNotFound", testTransactionNotFound},
+		{"ConstraintViolations", testConstraintViolations},
+		{"TimestampsUTC", testTimestampsUTC},
+		{"TransactionListByAccount", testTransactionListByAccount},
+		{"TransactionListPendingOlderThan", testTransactionListPendingOlderThan},
+		{"LedgerEvidence", testLedgerEvidence},
+		{"ConcurrentLockedUpdates", testConcurrentLockedUpdates},
+		{"CanceledContext", testCanceledContext},
+		{"LockWaitDeadline", testLockWaitDeadline},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newFixture(t))
+		})
+	}
+}
+
+func assertDecimal(t *testing.T, name string, got models.Decimal, want string) {
+	t.Helper()
+	gotValue, ok := new(big.Rat).SetString(string(got))
+	if !ok {
+		t.Fatalf("%s = %q, not a decimal", name, got)
+	}
+	wantValue, _ := new(big.Rat).SetString(want)
+	if gotValue.Cmp(wantValue) != 0 {
+		t.Errorf("%s = %s, want %s", name, got, want)
+	}
+}
+
+func mustCreateAccount(t *testing.T, f repositoryFixture, accountID int64, balance string) {
+	t.Helper()
+	if err := f.accounts.Create(context.Background(), accountID, models.Decimal(balance)); err != nil {
+		t.Fatalf("Create(%d) error = %v", accountID, err)
+	}
+}
+
+func mustBegin(t *testing.T, f repositoryFixture) Tx {
+	t.Helper()
+	tx, err := f.txManager.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	return tx
+}
+
+func mustCommit(t *testing.T, tx Tx) {
+	t.Helper()
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+}
+
+func mustCreateTransaction(t *testing.T, f repositoryFixture, source, destination int64, amount, status string) *models.Transaction {
+	t.Helper()
+	ctx := context.Background()
+	tx := mustBegin(t, f)
+	defer tx.Rollback()
+
+	transaction, err := f.transactions.Create(ctx, tx, source, destination, models.Decimal(amount))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if status != models.TransactionStatusPending {
+		if err := f.transactions.UpdateStatus(ctx, tx, transaction.ID, status); err != nil {
+			t.Fatalf("UpdateStatus() error = %v", err)
+		}
+		transaction.Status = status
+	}
+	mustCommit(t, tx)
+	return transaction
+}
+
+func testAccountCreateAndGet(t *testing.T, f repositoryFixture) {
+	ctx := context.Background()
+
+	mustCreateAccount(t, f, 1, "100.23344")
+
+	account, err := f.accounts.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if account.AccountID != 1 {
+		t.Errorf("AccountID = %d, want 1", account.AccountID)
+	}
+	assertDecimal(t, "Balance", account.Balance, "100.23344")
+
+	exists, err := f.accounts.Exists(ctx, 1)
+	if err != nil || !exists {
+		t.Errorf("Exists(1) = %v, %v; want true, nil", exists, err)
+	}
+	exists, err = f.accounts.Exists(ctx, 2)
+	if err != nil || exists {
+		t.Errorf("Exists(2) = %v, %v; want false, nil", exists, err)
+	}
+}
+
+func testAccountCreateDuplicate(t *testing.T, f repositoryFixture) {
+	ctx := context.Background()
+
+	mustCreateAccount(t, f, 1, "10")
+	if err := f.accounts.Create(ctx, 1, "20"); !errors.Is(err, ErrAccountExists) {
+		t.Fatalf("Create() with a duplicate ID error = %v, want ErrAccountExists", err)
+	}
+
+	account, err := f.accounts.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	assertDecimal(t, "Balance", account.Balance, "10")
+}
+
+func testAccountNotFound(t *testing.T, f repositoryFixture) {
+	ctx := context.Background()
+
+	if _, err := f.accounts.GetByID(ctx, 404); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("GetByID() error = %v, want ErrAccountNotFound", err)
+	}
+	if err := f.accounts.UpdateBalance(ctx, 404, "1"); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("UpdateBalance() error = %v, want ErrAccountNotFound", err)
+	}
+
+	tx := mustBegin(t, f)
+	defer tx.Rollback()
+	if _, err := f.accounts.GetByIDWithLock(ctx, tx, 404); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("GetByIDWithLock() error = %v, want ErrAccountNotFound", err)
+	}
+	if err := f.accounts.UpdateBalanceInTx(ctx, tx, 404, "1"); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("UpdateBalanceInTx() error = %v, want ErrAccountNotFound", err)
+	}
+}
+
+func testAccountUpdateBalance(t *testing.T, f repositoryFixture) {
+	ctx := context.Background()
+
+	mustCreateAccount(t, f, 1, "10")
+	if err := f.accounts.UpdateBalance(ctx, 1, "42.5"); err != nil {
+		t.Fatalf("UpdateBalance() error = %v", err)
+	}
+
+	account, err := f.accounts.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	assertDecimal(t, "Balance", account.Balance, "42.5")
+}
+
+func testTxCommit(t *testing.T, f repositoryFixture) {
+	ctx := context.Background()
+
+	mustCreateAccount(t, f, 1, "10")
+	mustCreateAccount(t, f, 2, "0")
+
+	tx := mustBegin(t, f)
+	defer tx.Rollback()
+
+	account, err := f.accounts.GetByIDWithLock(ctx, tx, 1)
+	if err != nil {
+		t.Fatalf("GetByIDWithLock() error = %v", err)
+	}
+	assertDecimal(t, "Balance", account.Balance, "10")
+	if err := f.accounts.UpdateBalanceInTx(ctx, tx, 1, "7"); err != nil {
+		t.Fatalf("UpdateBalanceInTx() error = %v", err)
+	}
+	transaction, err := f.transactions.Create(ctx, tx, 1, 2, "3")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	mustCommit(t, tx)
+
+	if err := tx.Rollback(); err == nil {
+		t.Error("Rollback() after Commit() succeeded, want error")
+	}
+
+	account, err = f.accounts.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	assertDecimal(t, "Balance", account.Balance, "7")
+	if _, err := f.transactions.GetByID(ctx, transaction.ID); err != nil {
+		t.Errorf("GetByID() of committed transaction error = %v", err)
+	}
+}
+
+func testTxRollback(t *testing.T, f repositoryFixture) {
+	ctx := context.Background()
+
+	mustCreateAccount(t, f, 1, "10")
+	mustCreateAccount(t, f, 2, "0")
+	committed := mustCreateTransaction(t, f, 1, 2, "1", models.TransactionStatusPending)
+
+	tx := mustBegin(t, f)
+	if err := f.accounts.UpdateBalanceInTx(ctx, tx, 1, "7"); err != nil {
+		t.Fatalf("UpdateBalanceInTx() error = %v", err)
+	}
+	rolledBack, err := f.transactions.Create(ctx, tx, 1, 2, "3")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := f.transactions.UpdateStatus(ctx, tx, committed.ID, models.TransactionStatusCompleted); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+
+	account, err := f.accounts.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	assertDecimal(t, "Balance", account.Balance, "10")
+	if _, err := f.transactions.GetByID(ctx, rolledBack.ID); !errors.Is(err, ErrTransactionNotFound) {
+		t.Errorf("GetByID() of rolled back transaction error = %v, want ErrTransactionNotFound", err)
+	}
+	transaction, err := f.transactions.GetByID(ctx, committed.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if transaction.Status != models.TransactionStatusPending {
+		t.Errorf("Status = %q after rollback, want pending", transaction.Status)
+	}
+}
+
+func testTransactionCreateAndStatus(t *testing.T, f repositoryFixture) {
+	ctx := context.Background()
+
+	mustCreateAccount(t, f, 1, "10")
+	mustCreateAccount(t, f, 2, "0")
+
+	tx := mustBegin(t, f)
+	defer tx.Rollback()
+	created, err := f.transactions.Create(ctx, tx, 1, 2, "2.5")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.ID <= 0 || created.Status != models.TransactionStatusPending || created.CreatedAt.IsZero() {
+		t.Errorf("Create() = %+v, want a pending transaction with ID and timestamps", created)
+	}
+	if created.SourceAccountID != 1 || created.DestinationAccountID != 2 {
+		t.Errorf("Create() accounts = %d -> %d, want 1 -> 2", created.SourceAccountID, created.DestinationAccountID)
+	}
+	assertDecimal(t, "Amount", created.Amount, "2.5")
+
+	locked, err := f.transactions.GetByIDWithLock(ctx, tx, created.ID)
+	if err != nil {
+		t.Fatalf("GetByIDWithLock() error = %v", err)
+	}
+	if locked.ID != created.ID {
+		t.Errorf("GetByIDWithLock() ID = %d, want %d", locked.ID, created.ID)
+	}
+	if err := f.transactions.MarkFailed(ctx, tx, created.ID, models.FailureReasonNotApplied); err != nil {
+		t.Fatalf("MarkFailed() error = %v", err)
+	}
+	mustCommit(t, tx)
+
+	transaction, err := f.transactions.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if transaction.Status != models.TransactionStatusFailed || transaction.FailureReason != models.FailureReasonNotApplied {
+		t.Errorf("GetByID() status = %q/%q, want failed/%s", transaction.Status, transaction.FailureReason, models.FailureReasonNotApplied)
+	}
+	if transaction.BalanceAtDecision != "" {
+		t.Errorf("BalanceAtDecision = %q, want empty", transaction.BalanceAtDecision)
+	}
+}
+
+func testTransactionCreateFailed(t *testing.T, f repositoryFixture) {
+	ctx := context.Background()
+
+	mustCreateAccount(t, f, 1, "10")
+	mustCreateAccount(t, f, 2, "0")
+
+	tx := mustBegin(t, f)
+	defer tx.Rollback()
+	created, err := f.transactions.CreateFailed(ctx, tx, 1, 2, "50", models.FailureReasonInsufficientFunds, "10")
+	if err != nil {
+		t.Fatalf("CreateFailed() error = %v", err)
+	}
+	mustCommit(t, tx)
+
+	transaction, err := f.transactions.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if transaction.Status != models.TransactionStatusFailed || transaction.FailureReason != models.FailureReasonInsufficientFunds {
+		t.Errorf("GetByID() status = %q/%q, want failed/%s", transaction.Status, transaction.FailureReason, models.FailureReasonInsufficientFunds)
+	}
+	assertDecimal(t, "BalanceAtDecision", transaction.BalanceAtDecision, "10")
+	assertDecimal(t, "Amount", transaction.Amount, "50")
+}
+
+func testTransactionNotFound(t *testing.T, f repositoryFixture) {
+	ctx := context.Background()
+
+	if _, err := f.transactions.GetByID(ctx, 404); !errors.Is(err, ErrTransactionNotFound) {
+		t.Errorf("GetByID() error = %v, want ErrTransactionNotFound", err)
+	}
+
+	tx := mustBegin(t, f)
+	defer tx.Rollback()
+	if _, err := f.transactions.GetByIDWithLock(ctx, tx, 404); !errors.Is(err, ErrTransactionNotFound) {
+		t.Errorf("GetByIDWithLock() error = %v, want ErrTransactionNotFound", err)
+	}
+	if _, err := f.transactions.Create(ctx, tx, 404, 405, "1"); !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("Create() between missing accounts error = %v, want ErrAccountNotFound", err)
+	}
+}
+
+// testConstraintViolations checks that writes the schema forbids are
+// rejected with the matching domain error. Each write gets its own Tx because
+// Postgres aborts a Tx after the first failed statement.
+func testConstraintViolations(t *testing.T, f repositoryFixture) {
+	ctx := context.Background()
+
+	mustCreateAccount(t, f, 1, "10")
+	mustCreateAccount(t, f, 2, "0")
+	pending := mustCreateTransaction(t, f, 1, 2, "1", models.TransactionStatusPending)
+
+	tests := []struct {
+		name    string
+		write   func(tx Tx) error
+		wantErr error
+	}{
+		{
+			name: "negative balance",
+			write: func(tx Tx) error {
+				return f.accounts.UpdateBalanceInTx(ctx, tx, 1, "-0.01")
+			},
+			wantErr: ErrInsufficientFunds,
+		},
+		{
+			name: "zero amount",
+			write: func(tx Tx) error {
+				_, err := f.transactions.Create(ctx, tx, 1, 2, "0")
+				return err
+			},
+			wantErr: ErrInvalidAmount,
+		},
+		{
+			name: "negative amount",
+			write: func(tx Tx) error {
+				_, err := f.transactions.CreateFailed(ctx, tx, 1, 2, "-5", models.FailureReasonInsufficientFunds, "10")
+				return err
+			},
+			wantErr: ErrInvalidAmount,
+		},
+		{
+			name: "self transfer",
+			write: func(tx Tx) error {
+				_, err := f.transactions.Create(ctx, tx, 1, 1, "1")
+				return err
+			},
+			wantErr: ErrSameAccount,
+		},
+		{
+			name: "unknown status",
+			write: func(tx Tx) error {
+				return f.transactions.UpdateStatus(ctx, tx, pending.ID, "refunded")
+			},
+			wantErr: ErrInvalidStatus,
+		},
+	}
+
+	for _, tt := range tests {
+		tx := mustBegin(t, f)
+		err := tt.write(tx)
+		tx.Rollback()
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	if err := f.accounts.Create(ctx, 3, "-1"); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("Create() with a negative balance error = %v, want ErrInsufficientFunds", err)
+	}
+	if err := f.accounts.UpdateBalance(ctx, 1, "-1"); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("UpdateBalance() below zero error = %v, want ErrInsufficientFunds", err)
+	}
+	account, err := f.accounts.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	assertDecimal(t, "Balance", account.Balance, "10")
+	assertDecimal(t, "OverdraftLimit", account.OverdraftLimit, "0")
+}
+
+func testTimestampsUTC(t *testing.T, f repositoryFixture) {
+	ctx := context.Background()
+	before := time.Now().Add(-time.Minute)
+
+	mustCreateAccount(t, f, 1, "10")
+	mustCreateAccount(t, f, 2, "0")
+	created := mustCreateTransaction(t, f, 1, 2, "1", models.TransactionStatusCompleted)
+	if err := f.accounts.UpdateBalance(ctx, 1, "9"); err != nil {
+		t.Fatalf("UpdateBalance() error = %v", err)
+	}
+	after := time.Now().Add(time.Minute)
+
+	account, err := f.accounts.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	transaction, err := f.transactions.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+
+	timestamps := map[string]time.Time{
+		"account created_at":     account.CreatedAt,
+		"account updated_at":     account.UpdatedAt,
+		"transaction created_at": transaction.CreatedAt,
+		"transaction updated_at": transaction.UpdatedAt,
+	}
+	for name, ts := range timestamps {
+		if ts.Location() != time.UTC {
+			t.Errorf("%s = %v, want UTC", name, ts)
+		}
+		// A time zone mix-up shifts timestamps by whole hours.
+		if ts.Before(before) || ts.After(after) {
+			t.Errorf("%s = %v, want between %v and %v", name, ts, before.UTC(), after.UTC())
+		}
+	}
+	if account.UpdatedAt.Before(account.CreatedAt) {
+		t.Errorf("account updated_at %v is before created_at %v", account.UpdatedAt, account.CreatedAt)
+	}
+}
+
+func testTransactionListByAccount(t *testing.T, f repositoryFixture) {
+	ctx := context.Background()
+
+	mustCreateAccount(t, f, 1, "100")
+	mustCreateAccount(t, f, 2, "100")
+	mustCreateAccount(t, f, 3, "100")
+
+	first := mustCreateTransaction(t, f, 1, 2, "1", models.TransactionStatusCompleted)
+	second := mustCreateTransaction(t, f, 2, 1, "2", models.TransactionStatusPending)
+	mustCreateTransaction(t, f, 2, 3, "3", models.TransactionStatusCompleted)
+	fourth := mustCreateTransaction(t, f, 3, 1, "4", models.TransactionStatusCompleted)
+
+	transactions, err := f.transactions.ListByAccount(ctx, 1, "", 0, 10)
+	if err != nil {
+		t.Fatalf("ListByAccount() error = %v", err)
+	}
+	assertTransactionIDs(t, transactions, fourth.ID, second.ID, first.ID)
+
+	transactions, err = f.transactions.ListByAccount(ctx, 1, models.TransactionStatusCompleted, 0, 10)
+	if err != nil {
+		t.Fatalf("ListByAccount() error = %v", err)
+	}
+	assertTransactionIDs(t, transactions, fourth.ID, first.ID)
+
+	transactions, err = f.transactions.ListByAccount(ctx, 1, "", fourth.ID, 1)
+	if err != nil {
+		t.Fatalf("ListByAccount() error = %v", err)
+	}
+	assertTransactionIDs(t, transactions, second.ID)
+}
+
+func assertTransactionIDs(t *testing.T, transactions []models.Transaction, want ...int64) {
+	t.Helper()
+	got := make([]int64, len(transactions))
+	for i := range transactions {
+		got[i] = transactions[i].ID
+	}
+	if len(got) != len(want) {
+		t.Fatalf("transaction IDs = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("transaction IDs = %v, want %v", got, want)
+		}
+	}
+}
+
+func testTransactionListPendingOlderThan(t *testing.T, f repositoryFixture) {
+	ctx := context.Background()
+
+	mustCreateAccount(t, f, 1, "100")
+	mustCreateAccount(t, f, 2, "100")
+
+	pending := mustCreateTransaction(t, f, 1, 2, "1", models.TransactionStatusPending)
+	mustCreateTransaction(t, f, 1, 2, "2", models.TransactionStatusCompleted)
+	time.Sleep(20 * time.Millisecond)
+
+	transactions, err := f.transactions.ListPendingOlderThan(ctx, 10*time.Millisecond, 10)
+	if err != nil {
+		t.Fatalf("ListPendingOlderThan() error = %v", err)
+	}
+	assertTransactionIDs(t, transactions, pending.ID)
+
+	transactions, err = f.transactions.ListPendingOlderThan(ctx, time.Hour, 10)
+	if err != nil {
+		t.Fatalf("ListPendingOlderThan() error = %v", err)
+	}
+	assertTransactionIDs(t, transactions)
+}
+
+func testLedgerEvidence(t *testing.T, f repositoryFixture) {
+	ctx := context.Background()
+
+	mustCreateAccount(t, f, 1, "100")
+	mustCreateAccount(t, f, 2, "50")
+	mustCreateAccount(t, f, 3, "0")
+
+	notApplied := mustCreateTransaction(t, f, 1, 2, "10", models.TransactionStatusPending)
+
+	// Move the balances of accounts 1 and 3 as if a second pending transfer
+	// had been applied without being marked completed.
+	applied := mustCreateTransaction(t, f, 1, 3, "30", models.TransactionStatusPending)
+	if err := f.accounts.UpdateBalance(ctx, 1, "70"); err != nil {
+		t.Fatalf("UpdateBalance() error = %v", err)
+	}
+	if err := f.accounts.UpdateBalance(ctx, 3, "30"); err != nil {
+		t.Fatalf("UpdateBalance() error = %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		transaction    *models.Transaction
+		wantApplied    bool
+		wantNotApplied bool
+	}{
+		{"source balance moved by another transfer", notApplied, false, false},
+		{"balances include the transfer", applied, true, false},
+	}
+	for _, tt := range tests {
+		tx := mustBegin(t, f)
+		gotApplied, gotNotApplied, err := f.transactions.LedgerEvidence(ctx, tx, tt.transaction)
+		tx.Rollback()
+		if err != nil {
+			t.Fatalf("%s: LedgerEvidence() error = %v", tt.name, err)
+		}
+		if gotApplied != tt.wantApplied || gotNotApplied != tt.wantNotApplied {
+			t.Errorf("%s: LedgerEvidence() = %v, %v; want %v, %v",
+				tt.name, gotApplied, gotNotApplied, tt.wantApplied, tt.wantNotApplied)
+		}
+	}
+
+	// Once the other transfer is completed, the ledger explains account 1
+	// again and shows the first transfer was never applied.
+	tx := mustBegin(t, f)
+	if err := f.transactions.UpdateStatus(ctx, tx, applied.ID, models.TransactionStatusCompleted); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	mustCommit(t, tx)
+
+	tx = mustBegin(t, f)
+	defer tx.Rollback()
+	gotApplied, gotNotApplied, err := f.transactions.LedgerEvidence(ctx, tx, notApplied)
+	if err != nil {
+		t.Fatalf("LedgerEvidence() error = %v", err)
+	}
+	if gotApplied || !gotNotApplied {
+		t.Errorf("LedgerEvidence() = %v, %v; want false, true", gotApplied, gotNotApplied)
+	}
+}
+
+// testConcurrentLockedUpdates increments one balance from many goroutines
+// using read-with-lock then update; lost updates mean locks are not held.
+func testConcurrentLockedUpdates(t *testing.T, f repositoryFixture) {
+	ctx := context.Background()
+
+	mustCreateAccount(t, f, 1, "0")
+
+	const workers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tx, err := f.txManager.Begin(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer tx.Rollback()
+
+			account, err := f.accounts.GetByIDWithLock(ctx, tx, 1)
+			if err != nil {
+				errs <- err
+				return
+			}
+			balance, _ := new(big.Rat).SetString(string(account.Balance))
+			balance.Add(balance, big.NewRat(1, 1))
+			if err := f.accounts.UpdateBalanceInTx(ctx, tx, 1, models.Decimal(balance.FloatString(0))); err != nil {
+				errs <- err
+				return
+			}
+			errs <- tx.Commit()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent update error = %v", err)
+		}
+	}
+
+	account, err := f.accounts.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	assertDecimal(t, "Balance", account.Balance, "20")
+}
+
+func testCanceledContext(t *testing.T, f repositoryFixture) {
+	mustCreateAccount(t, f, 1, "10")
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := f.txManager.Begin(canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Begin() error = %v, want context.Canceled", err)
+	}
+	if _, err := f.accounts.GetByID(canceled, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetByID() error = %v, want context.Canceled", err)
+	}
+	if _, err := f.transactions.ListByAccount(canceled, 1, "", 0, 10); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListByAccount() error = %v, want context.Canceled", err)
+	}
+
+	// Canceling the context of a running Tx rolls it back.
+	ctx, cancel := context.WithCancel(context.Background())
+	tx, err := f.txManager.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := f.accounts.UpdateBalanceInTx(ctx, tx, 1, "99"); err != nil {
+		t.Fatalf("UpdateBalanceInTx() error = %v", err)
+	}
+	cancel()
+	if err := tx.Commit(); err == nil {
+		t.Error("Commit() after cancel succeeded, want error")
+	}
+	tx.Rollback()
+
+	account, err := f.accounts.GetByID(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	assertDecimal(t, "Balance", account.Balance, "10")
+}
+
+// testLockWaitDeadline makes sure that waiting for a row held by another Tx
+// gives up once the waiter's deadline expires.
+func testLockWaitDeadline(t *testing.T, f repositoryFixture) {
+	ctx := context.Background()
+
+	mustCreateAccount(t, f, 1, "10")
+
+	holder := mustBegin(t, f)
+	defer holder.Rollback()
+	if _, err := f.accounts.GetByIDWithLock(ctx, holder, 1); err != nil {
+		t.Fatalf("GetByIDWithLock() error = %v", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	tx, err := f.txManager.Begin(waitCtx)
+	if err == nil {
+		_, err = f.accounts.GetByIDWithLock(waitCtx, tx, 1)
+		tx.Rollback()
+	}
+	if err == nil {
+		t.Fatal("locking a held row succeeded, want deadline error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("lock wait took %v after a 50ms deadline", elapsed)
+	}
+	mustCommit(t, holder)
+}
@@ -0,0 +1,8 @@
+package repository
+
+// Exported for package repository_test, which runs the services against
+// Postgres; the service package cannot be imported from here.
+var (
+	OpenTestDatabase = openTestDatabase
+	TruncateTables   = truncateTables
+)
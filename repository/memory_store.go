@@ -0,0 +1,541 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"triplea-backend-assignment/models"
+)
+
+// decimalScale matches the DECIMAL(20, 10) columns so that values read back
+// from the in-memory store look exactly like values read from Postgres.
+const decimalScale = 10
+
+// MemoryStore is an in-memory implementation of the account and transaction
+// repositories, intended for tests. A Tx holds the store's lock from Begin
+// until Commit or Rollback, so transactions are serializable and calls made
+// outside a Tx wait for the running one to finish. The lock is a channel so
+// that waiting for it can be abandoned when the context is done.
+type MemoryStore struct {
+	sem          chan struct{}
+	accounts     map[int64]*memoryAccount
+	transactions map[int64]*models.Transaction
+	order        []int64
+	nextID       int64
+}
+
+type memoryAccount struct {
+	account        models.Account
+	initialBalance models.Decimal
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sem:          make(chan struct{}, 1),
+		accounts:     make(map[int64]*memoryAccount),
+		transactions: make(map[int64]*models.Transaction),
+	}
+}
+
+func (s *MemoryStore) Accounts() AccountRepository {
+	return &memoryAccountRepository{store: s}
+}
+
+func (s *MemoryStore) Transactions() TransactionRepository {
+	return &memoryTransactionRepository{store: s}
+}
+
+func (s *MemoryStore) lock(ctx context.Context) error {
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	// select picks at random when both cases are ready.
+	if err := ctx.Err(); err != nil {
+		s.unlock()
+		return err
+	}
+	return nil
+}
+
+func (s *MemoryStore) unlock() {
+	<-s.sem
+}
+
+func (s *MemoryStore) Begin(ctx context.Context) (Tx, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	return &memoryTx{store: s, ctx: ctx}, nil
+}
+
+type memoryTx struct {
+	store *MemoryStore
+	ctx   context.Context
+	undo  []func()
+	done  bool
+}
+
+// Commit rolls back instead when the Tx's context is done, like *sql.Tx.
+func (t *memoryTx) Commit() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	if err := t.ctx.Err(); err != nil {
+		t.Rollback()
+		return err
+	}
+	t.done = true
+	t.undo = nil
+	t.store.unlock()
+	return nil
+}
+
+func (t *memoryTx) Rollback() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		t.undo[i]()
+	}
+	t.undo = nil
+	t.store.unlock()
+	return nil
+}
+
+func (s *MemoryStore) unwrapTx(ctx context.Context, tx Tx) (*memoryTx, error) {
+	memTx, ok := tx.(*memoryTx)
+	if !ok || memTx.store != s {
+		return nil, fmt.Errorf("unsupported transaction type %T", tx)
+	}
+	if memTx.done {
+		return nil, sql.ErrTxDone
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := memTx.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return memTx, nil
+}
+
+type memoryAccountRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryAccountRepository) Create(ctx context.Context, accountID int64, balance models.Decimal) error {
+	normalized, err := normalizeDecimal(balance)
+	if err != nil {
+		return fmt.Errorf("failed to create account: %w", err)
+	}
+
+	if err := r.store.lock(ctx); err != nil {
+		return fmt.Errorf("failed to create account: %w", err)
+	}
+	defer r.store.unlock()
+
+	if _, ok := r.store.accounts[accountID]; ok {
+		return ErrAccountExists
+	}
+	now := time.Now().UTC()
+	account := models.Account{
+		AccountID:      accountID,
+		Balance:        normalized,
+		OverdraftLimit: "0.0000000000",
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := checkOverdraft(&account, normalized); err != nil {
+		return err
+	}
+	r.store.accounts[accountID] = &memoryAccount{
+		account:        account,
+		initialBalance: normalized,
+	}
+	return nil
+}
+
+func (r *memoryAccountRepository) GetByID(ctx context.Context, accountID int64) (*models.Account, error) {
+	if err := r.store.lock(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	defer r.store.unlock()
+
+	return r.get(accountID)
+}
+
+func (r *memoryAccountRepository) UpdateBalance(ctx context.Context, accountID int64, newBalance models.Decimal) error {
+	normalized, err := normalizeDecimal(newBalance)
+	if err != nil {
+		return fmt.Errorf("failed to update account balance: %w", err)
+	}
+
+	if err := r.store.lock(ctx); err != nil {
+		return fmt.Errorf("failed to update account balance: %w", err)
+	}
+	defer r.store.unlock()
+
+	account, ok := r.store.accounts[accountID]
+	if !ok {
+		return ErrAccountNotFound
+	}
+	if err := checkOverdraft(&account.account, normalized); err != nil {
+		return err
+	}
+	account.account.Balance = normalized
+	account.account.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+func (r *memoryAccountRepository) Exists(ctx context.Context, accountID int64) (bool, error) {
+	if err := r.store.lock(ctx); err != nil {
+		return false, fmt.Errorf("failed to check account existence: %w", err)
+	}
+	defer r.store.unlock()
+
+	_, ok := r.store.accounts[accountID]
+	return ok, nil
+}
+
+func (r *memoryAccountRepository) GetByIDWithLock(ctx context.Context, tx Tx, accountID int64) (*models.Account, error) {
+	if _, err := r.store.unwrapTx(ctx, tx); err != nil {
+		return nil, err
+	}
+	return r.get(accountID)
+}
+
+func (r *memoryAccountRepository) UpdateBalanceInTx(ctx context.Context, tx Tx, accountID int64, newBalance models.Decimal) error {
+	memTx, err := r.store.unwrapTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+	normalized, err := normalizeDecimal(newBalance)
+	if err != nil {
+		return fmt.Errorf("failed to update account balance: %w", err)
+	}
+
+	account, ok := r.store.accounts[accountID]
+	if !ok {
+		return ErrAccountNotFound
+	}
+	if err := checkOverdraft(&account.account, normalized); err != nil {
+		return err
+	}
+	previous := account.account
+	memTx.undo = append(memTx.undo, func() { account.account = previous })
+	account.account.Balance = normalized
+	account.account.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+func (r *memoryAccountRepository) get(accountID int64) (*models.Account, error) {
+	account, ok := r.store.accounts[accountID]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+	result := account.account
+	return &result, nil
+}
+
+type memoryTransactionRepository struct {
+	store *MemoryStore
+}
+
+func (r *memoryTransactionRepository) Create(ctx context.Context, tx Tx, sourceAccountID, destinationAccountID int64, amount models.Decimal) (*models.Transaction, error) {
+	transaction, err := r.insert(ctx, tx, &models.Transaction{
+		SourceAccountID:      sourceAccountID,
+		DestinationAccountID: destinationAccountID,
+		Amount:               amount,
+		Status:               models.TransactionStatusPending,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	return transaction, nil
+}
+
+func (r *memoryTransactionRepository) CreateFailed(
+	ctx context.Context,
+	tx Tx,
+	sourceAccountID, destinationAccountID int64,
+	amount models.Decimal,
+	reason string,
+	balanceAtDecision models.Decimal,
+) (*models.Transaction, error) {
+	balance := balanceAtDecision
+	if balance != "" {
+		normalized, err := normalizeDecimal(balance)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create failed transaction: %w", err)
+		}
+		balance = normalized
+	}
+	transaction, err := r.insert(ctx, tx, &models.Transaction{
+		SourceAccountID:      sourceAccountID,
+		DestinationAccountID: destinationAccountID,
+		Amount:               amount,
+		Status:               models.TransactionStatusFailed,
+		FailureReason:        reason,
+		BalanceAtDecision:    balance,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create failed transaction: %w", err)
+	}
+	return transaction, nil
+}
+
+// insert mirrors the constraints of the transactions table. Like a Postgres
+// sequence, the ID counter is not rolled back with the Tx.
+func (r *memoryTransactionRepository) insert(ctx context.Context, tx Tx, transaction *models.Transaction) (*models.Transaction, error) {
+	memTx, err := r.store.unwrapTx(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := normalizeDecimal(transaction.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if value, _ := parseDecimal(amount); value.Sign() <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if transaction.SourceAccountID == transaction.DestinationAccountID {
+		return nil, ErrSameAccount
+	}
+	if _, ok := r.store.accounts[transaction.SourceAccountID]; !ok {
+		return nil, ErrAccountNotFound
+	}
+	if _, ok := r.store.accounts[transaction.DestinationAccountID]; !ok {
+		return nil, ErrAccountNotFound
+	}
+
+	r.store.nextID++
+	now := time.Now().UTC()
+	transaction.ID = r.store.nextID
+	transaction.Amount = amount
+	transaction.CreatedAt = now
+	transaction.UpdatedAt = now
+
+	r.store.transactions[transaction.ID] = transaction
+	r.store.order = append(r.store.order, transaction.ID)
+	memTx.undo = append(memTx.undo, func() { delete(r.store.transactions, transaction.ID) })
+
+	result := *transaction
+	return &result, nil
+}
+
+func (r *memoryTransactionRepository) UpdateStatus(ctx context.Context, tx Tx, transactionID int64, status string) error {
+	if err := r.update(ctx, tx, transactionID, status, ""); err != nil {
+		return fmt.Errorf("failed to update transaction status: %w", err)
+	}
+	return nil
+}
+
+func (r *memoryTransactionRepository) MarkFailed(ctx context.Context, tx Tx, transactionID int64, reason string) error {
+	if err := r.update(ctx, tx, transactionID, models.TransactionStatusFailed, reason); err != nil {
+		return fmt.Errorf("failed to mark transaction failed: %w", err)
+	}
+	return nil
+}
+
+// update matches the Postgres UPDATE statements, which succeed without
+// touching anything when the row does not exist.
+func (r *memoryTransactionRepository) update(ctx context.Context, tx Tx, transactionID int64, status, reason string) error {
+	memTx, err := r.store.unwrapTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if !models.IsValidTransactionStatus(status) {
+		return ErrInvalidStatus
+	}
+	transaction, ok := r.store.transactions[transactionID]
+	if !ok {
+		return nil
+	}
+	previous := *transaction
+	memTx.undo = append(memTx.undo, func() { *transaction = previous })
+
+	transaction.Status = status
+	if reason != "" {
+		transaction.FailureReason = reason
+	}
+	transaction.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+func (r *memoryTransactionRepository) GetByID(ctx context.Context, transactionID int64) (*models.Transaction, error) {
+	if err := r.store.lock(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	defer r.store.unlock()
+
+	return r.get(transactionID)
+}
+
+func (r *memoryTransactionRepository) GetByIDWithLock(ctx context.Context, tx Tx, transactionID int64) (*models.Transaction, error) {
+	if _, err := r.store.unwrapTx(ctx, tx); err != nil {
+		return nil, err
+	}
+	return r.get(transactionID)
+}
+
+func (r *memoryTransactionRepository) get(transactionID int64) (*models.Transaction, error) {
+	transaction, ok := r.store.transactions[transactionID]
+	if !ok {
+		return nil, ErrTransactionNotFound
+	}
+	result := *transaction
+	return &result, nil
+}
+
+func (r *memoryTransactionRepository) ListByAccount(ctx context.Context, accountID int64, status string, beforeID int64, limit int) ([]models.Transaction, error) {
+	if err := r.store.lock(ctx); err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+	defer r.store.unlock()
+
+	transactions := []models.Transaction{}
+	for i := len(r.store.order) - 1; i >= 0 && len(transactions) < limit; i-- {
+		transaction, ok := r.store.transactions[r.store.order[i]]
+		if !ok {
+			continue
+		}
+		if transaction.SourceAccountID != accountID && transaction.DestinationAccountID != accountID {
+			continue
+		}
+		if status != "" && transaction.Status != status {
+			continue
+		}
+		if beforeID != 0 && transaction.ID >= beforeID {
+			continue
+		}
+		transactions = append(transactions, *transaction)
+	}
+	return transactions, nil
+}
+
+func (r *memoryTransactionRepository) ListPendingOlderThan(ctx context.Context, age time.Duration, limit int) ([]models.Transaction, error) {
+	if err := r.store.lock(ctx); err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+	defer r.store.unlock()
+
+	cutoff := time.Now().Add(-age)
+	transactions := []models.Transaction{}
+	for _, id := range r.store.order {
+		transaction, ok := r.store.transactions[id]
+		if !ok {
+			continue
+		}
+		if transaction.Status == models.TransactionStatusPending && transaction.CreatedAt.Before(cutoff) {
+			transactions = append(transactions, *transaction)
+		}
+	}
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].CreatedAt.Before(transactions[j].CreatedAt)
+	})
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+	}
+	return transactions, nil
+}
+
+func (r *memoryTransactionRepository) LedgerEvidence(ctx context.Context, tx Tx, transaction *models.Transaction) (bool, bool, error) {
+	if _, err := r.store.unwrapTx(ctx, tx); err != nil {
+		return false, false, err
+	}
+	amount, err := parseDecimal(transaction.Amount)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to read ledger evidence: %w", err)
+	}
+
+	sourceBalance, sourceExpected, err := r.ledgerBalance(transaction.SourceAccountID)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to read ledger evidence: %w", err)
+	}
+	destBalance, destExpected, err := r.ledgerBalance(transaction.DestinationAccountID)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to read ledger evidence: %w", err)
+	}
+
+	applied := sourceBalance.Cmp(new(big.Rat).Sub(sourceExpected, amount)) == 0 &&
+		destBalance.Cmp(new(big.Rat).Add(destExpected, amount)) == 0
+	notApplied := sourceBalance.Cmp(sourceExpected) == 0 && destBalance.Cmp(destExpected) == 0
+	return applied, notApplied, nil
+}
+
+// ledgerBalance returns the account's balance and the balance explained by
+// its initial balance and completed transactions.
+func (r *memoryTransactionRepository) ledgerBalance(accountID int64) (*big.Rat, *big.Rat, error) {
+	account, ok := r.store.accounts[accountID]
+	if !ok {
+		return nil, nil, ErrAccountNotFound
+	}
+	balance, err := parseDecimal(account.account.Balance)
+	if err != nil {
+		return nil, nil, err
+	}
+	expected, err := parseDecimal(account.initialBalance)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, transaction := range r.store.transactions {
+		if transaction.Status != models.TransactionStatusCompleted {
+			continue
+		}
+		amount, err := parseDecimal(transaction.Amount)
+		if err != nil {
+			return nil, nil, err
+		}
+		if transaction.DestinationAccountID == accountID {
+			expected.Add(expected, amount)
+		}
+		if transaction.SourceAccountID == accountID {
+			expected.Sub(expected, amount)
+		}
+	}
+	return balance, expected, nil
+}
+
+// checkOverdraft mirrors the accounts_balance_within_overdraft constraint.
+func checkOverdraft(account *models.Account, balance models.Decimal) error {
+	value, err := parseDecimal(balance)
+	if err != nil {
+		return err
+	}
+	limit, err := parseDecimal(account.OverdraftLimit)
+	if err != nil {
+		return err
+	}
+	if value.Cmp(new(big.Rat).Neg(limit)) < 0 {
+		return ErrInsufficientFunds
+	}
+	return nil
+}
+
+func parseDecimal(d models.Decimal) (*big.Rat, error) {
+	if strings.Contains(string(d), "/") {
+		return nil, fmt.Errorf("invalid decimal value %q", string(d))
+	}
+	value, ok := new(big.Rat).SetString(string(d))
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal value %q", string(d))
+	}
+	return value, nil
+}
+
+func normalizeDecimal(d models.Decimal) (models.Decimal, error) {
+	value, err := parseDecimal(d)
+	if err != nil {
+		return "", err
+	}
+	return models.Decimal(value.FloatString(decimalScale)), nil
+}
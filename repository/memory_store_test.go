@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"testing"
+)
+
+func TestMemoryStoreConformance(t *testing.T) {
+	runConformance(t, func(t *testing.T) repositoryFixture {
+		store := NewMemoryStore()
+		return repositoryFixture{
+			txManager:    store,
+			accounts:     store.Accounts(),
+			transactions: store.Transactions(),
+		}
+	})
+}
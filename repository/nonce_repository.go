@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"triplea-backend-assignment/database"
+)
+
+// NonceRepository remembers the nonces of signed requests. It lives in the
+// database so that a request replayed against another instance is caught
+// too.
+type NonceRepository struct {
+	store *database.Store
+
+	remember      *database.Statement
+	deleteExpired *database.Statement
+}
+
+func NewNonceRepository(ctx context.Context, store *database.Store) (*NonceRepository, error) {
+	r := &NonceRepository{store: store}
+	err := prepareStatements(ctx, store, []statementSpec{
+		{&r.remember, "NonceRepository.Remember",
+			`INSERT INTO request_nonces (client_id, nonce, expires_at) VALUES ($1, $2, $3)
+			 ON CONFLICT (client_id, nonce) DO NOTHING`},
+		{&r.deleteExpired, "NonceRepository.DeleteExpired",
+			`DELETE FROM request_nonces WHERE expires_at < $1`},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Remember records nonce for clientID until expiresAt. It returns false if
+// the nonce was already recorded.
+func (r *NonceRepository) Remember(ctx context.Context, clientID, nonce string, expiresAt time.Time) (bool, error) {
+	result, err := r.remember.On(ctx, r.store.DB()).ExecContext(ctx, clientID, nonce, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to record nonce: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected == 1, nil
+}
+
+// DeleteExpired deletes the nonces that expired before now and returns how
+// many there were.
+func (r *NonceRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.deleteExpired.On(ctx, r.store.DB()).ExecContext(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired nonces: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+	"triplea-backend-assignment/database"
+	"triplea-backend-assignment/models"
+)
+
+var partitionNamePattern = regexp.MustCompile(`^transactions_p\d{6}$`)
+
+// parsePartitionName is the inverse of models.MonthPartition. It reports false for
+// tables that are not monthly partitions, such as transactions_default.
+func parsePartitionName(name string) (models.TransactionPartition, bool) {
+	if !partitionNamePattern.MatchString(name) {
+		return models.TransactionPartition{}, false
+	}
+	start, err := time.Parse(models.PartitionNameLayout, name[len(models.PartitionNamePrefix):])
+	if err != nil {
+		return models.TransactionPartition{}, false
+	}
+	return models.MonthPartition(start), true
+}
+
+type PartitionRepository struct {
+	store *database.Store
+}
+
+func NewPartitionRepository(store *database.Store) *PartitionRepository {
+	return &PartitionRepository{store: store}
+}
+
+// List returns the monthly partitions currently attached to transactions,
+// oldest first.
+func (r *PartitionRepository) List(ctx context.Context) ([]models.TransactionPartition, error) {
+	query := `SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+			  WHERE i.inhparent = 'transactions'::regclass`
+	rows, err := r.store.DB().QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+	defer rows.Close()
+
+	partitions := []models.TransactionPartition{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan partition: %w", err)
+		}
+		if partition, ok := parsePartitionName(name); ok {
+			partitions = append(partitions, partition)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].Start.Before(partitions[j].Start)
+	})
+	return partitions, nil
+}
+
+// Create adds the partition for a month, moving any of its rows out of the
+// default partition first so that attaching it does not fail.
+func (r *PartitionRepository) Create(ctx context.Context, partition models.TransactionPartition) error {
+	tx, err := r.store.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	name := pq.QuoteIdentifier(partition.Name)
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE `+name+` (LIKE transactions INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`); err != nil {
+		return fmt.Errorf("failed to create partition %s: %w", partition.Name, err)
+	}
+	query := `WITH moved AS (
+				DELETE FROM transactions_default WHERE created_at >= $1 AND created_at < $2 RETURNING *
+			  )
+			  INSERT INTO ` + name + ` SELECT * FROM moved`
+	if _, err := tx.ExecContext(ctx, query, partition.Start, partition.End); err != nil {
+		return fmt.Errorf("failed to move rows into partition %s: %w", partition.Name, err)
+	}
+	// Partition bounds cannot be bind parameters.
+	query = fmt.Sprintf(`ALTER TABLE transactions ATTACH PARTITION %s FOR VALUES FROM (%s) TO (%s)`,
+		name, pq.QuoteLiteral(partition.Start.Format(time.RFC3339)), pq.QuoteLiteral(partition.End.Format(time.RFC3339)))
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to attach partition %s: %w", partition.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit partition %s: %w", partition.Name, err)
+	}
+	return nil
+}
+
+// Archive hands every row of a partition to write and calls flush once all
+// rows are written. Only if flush succeeds is the partition detached and
+// dropped, with its completed transfers added to archived_account_balances
+// in place of their ledger entries so ledger checks still explain current
+// balances. Writes to the partition are blocked meanwhile, and a partition
+// with pending transactions is refused because the sweeper may still resolve
+// them.
+func (r *PartitionRepository) Archive(
+	ctx context.Context,
+	partition models.TransactionPartition,
+	fileName string,
+	write func(transaction *models.Transaction) error,
+	flush func() error,
+) (int64, error) {
+	tx, err := r.store.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Exporting a whole month may take longer than a request is allowed to.
+	if _, err := tx.ExecContext(ctx, `SET LOCAL statement_timeout = 0`); err != nil {
+		return 0, fmt.Errorf("failed to disable statement timeout: %w", err)
+	}
+	name := pq.QuoteIdentifier(partition.Name)
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE `+name+` IN SHARE MODE`); err != nil {
+		return 0, fmt.Errorf("failed to lock partition %s: %w", partition.Name, err)
+	}
+
+	var pending bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM `+name+` WHERE status = $1)`,
+		models.TransactionStatusPending).Scan(&pending)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check partition %s for pending transactions: %w", partition.Name, err)
+	}
+	if pending {
+		return 0, fmt.Errorf("partition %s still has pending transactions", partition.Name)
+	}
+
+	count, err := exportRows(ctx, tx, `SELECT `+transactionColumns+` FROM `+name+` ORDER BY id`, write)
+	if err != nil {
+		return 0, fmt.Errorf("failed to export partition %s: %w", partition.Name, err)
+	}
+	if err := flush(); err != nil {
+		return 0, fmt.Errorf("failed to write archive of partition %s: %w", partition.Name, err)
+	}
+
+	query := `INSERT INTO archived_account_balances (account_id, net_amount)
+			  SELECT account_id, SUM(amount) FROM (
+				SELECT destination_account_id AS account_id, amount FROM ` + name + ` WHERE status = $1
+				UNION ALL
+				SELECT source_account_id, -amount FROM ` + name + ` WHERE status = $1
+			  ) movements
+			  GROUP BY account_id
+			  ON CONFLICT (account_id) DO UPDATE
+				SET net_amount = archived_account_balances.net_amount + EXCLUDED.net_amount`
+	if _, err := tx.ExecContext(ctx, query, models.TransactionStatusCompleted); err != nil {
+		return 0, fmt.Errorf("failed to record archived balances: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM ledger_entries WHERE transaction_id IN (SELECT id FROM `+name+`)`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete ledger entries of partition %s: %w", partition.Name, err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO transaction_archives (partition_name, range_start, range_end, row_count, file_name)
+								  VALUES ($1, $2, $3, $4, $5)`,
+		partition.Name, partition.Start, partition.End, count, fileName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record archive of partition %s: %w", partition.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE transactions DETACH PARTITION `+name); err != nil {
+		return 0, fmt.Errorf("failed to detach partition %s: %w", partition.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DROP TABLE `+name); err != nil {
+		return 0, fmt.Errorf("failed to drop partition %s: %w", partition.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit archive of partition %s: %w", partition.Name, err)
+	}
+	return count, nil
+}
+
+func exportRows(ctx context.Context, tx *sql.Tx, query string, write func(transaction *models.Transaction) error) (int64, error) {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var count int64
+	for rows.Next() {
+		var transaction models.Transaction
+		if err := scanTransaction(rows, &transaction); err != nil {
+			return count, err
+		}
+		if err := write(&transaction); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePartitionName(t *testing.T) {
+	tests := []struct {
+		name      string
+		wantOK    bool
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{name: "transactions_p202401", wantOK: true,
+			wantStart: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), wantEnd: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "transactions_p202412", wantOK: true,
+			wantStart: time.Date(2024, time.December, 1, 0, 0, 0, 0, time.UTC), wantEnd: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "transactions_default"},
+		{name: "transactions_p202413"},
+		{name: "transactions_p2024011"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			partition, ok := parsePartitionName(tt.name)
+			if ok != tt.wantOK {
+				t.Fatalf("parsePartitionName() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if partition.Name != tt.name || !partition.Start.Equal(tt.wantStart) || !partition.End.Equal(tt.wantEnd) {
+				t.Errorf("parsePartitionName() = %+v, want [%v, %v)", partition, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// constraintError translates an integrity constraint violation reported by
+// Postgres into the matching domain error, or returns nil for anything else.
+func constraintError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return nil
+	}
+
+	switch pqErr.Code.Name() {
+	case "check_violation":
+		switch pqErr.Constraint {
+		case "accounts_balance_within_overdraft":
+			return ErrInsufficientFunds
+		case "transactions_amount_positive":
+			return ErrInvalidAmount
+		case "transactions_distinct_accounts":
+			return ErrSameAccount
+		}
+	case "unique_violation":
+		if pqErr.Constraint == "accounts_pkey" {
+			return ErrAccountExists
+		}
+	case "foreign_key_violation":
+		return ErrAccountNotFound
+	case "invalid_text_representation":
+		if strings.Contains(pqErr.Message, "transaction_status") {
+			return ErrInvalidStatus
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/repository"
+	"triplea-backend-assignment/service"
+)
+
+type nopTransferRecorder struct{}
+
+func (nopTransferRecorder) RecordTransfer(result string, amount float64) {}
+
+// Transfers in opposite directions lock the same two accounts. Unless they
+// lock them in the same order, Postgres aborts one with a deadlock.
+func TestPostgresOppositeTransfersDoNotDeadlock(t *testing.T) {
+	store, db := repository.OpenTestDatabase(t)
+	repository.TruncateTables(t, db)
+	ctx := context.Background()
+
+	accounts, err := repository.NewPostgresAccountRepository(ctx, store)
+	if err != nil {
+		t.Fatalf("NewPostgresAccountRepository() error = %v", err)
+	}
+	transactions, err := repository.NewPostgresTransactionRepository(ctx, store)
+	if err != nil {
+		t.Fatalf("NewPostgresTransactionRepository() error = %v", err)
+	}
+	for _, id := range []int64{1, 2} {
+		if err := accounts.Create(ctx, id, "1000"); err != nil {
+			t.Fatalf("Create(%d) error = %v", id, err)
+		}
+	}
+	svc := service.NewTransactionService(repository.NewPostgresTxManager(store), transactions, accounts, nopTransferRecorder{}, nil)
+
+	const perDirection = 50
+	errs := make(chan error, 2*perDirection)
+	var wg sync.WaitGroup
+	for i := 0; i < perDirection; i++ {
+		for _, direction := range [][2]int64{{1, 2}, {2, 1}} {
+			wg.Add(1)
+			go func(source, destination int64) {
+				defer wg.Done()
+				err := svc.ProcessTransaction(ctx, &models.CreateTransactionRequest{
+					SourceAccountID: source, DestinationAccountID: destination, Amount: "1"})
+				if err != nil {
+					errs <- fmt.Errorf("transfer %d→%d: %w", source, destination, err)
+				}
+			}(direction[0], direction[1])
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	for _, id := range []int64{1, 2} {
+		account, err := accounts.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID(%d) error = %v", id, err)
+		}
+		if account.Balance != "1000.0000000000" {
+			t.Errorf("account %d balance = %s, want 1000", id, account.Balance)
+		}
+	}
+}
+
+// The sweeper locks a pending transaction and then both of its accounts,
+// while transfers lock their accounts only. Sweeps of pending transfers in
+// both directions running alongside transfers in both directions must all
+// resolve without a deadlock.
+func TestPostgresSweepsAndOppositeTransfersDoNotDeadlock(t *testing.T) {
+	store, db := repository.OpenTestDatabase(t)
+	repository.TruncateTables(t, db)
+	ctx := context.Background()
+
+	accounts, err := repository.NewPostgresAccountRepository(ctx, store)
+	if err != nil {
+		t.Fatalf("NewPostgresAccountRepository() error = %v", err)
+	}
+	transactions, err := repository.NewPostgresTransactionRepository(ctx, store)
+	if err != nil {
+		t.Fatalf("NewPostgresTransactionRepository() error = %v", err)
+	}
+	for _, id := range []int64{1, 2} {
+		if err := accounts.Create(ctx, id, "1000"); err != nil {
+			t.Fatalf("Create(%d) error = %v", id, err)
+		}
+	}
+	txManager := repository.NewPostgresTxManager(store)
+
+	// Pending transfers alternate direction, and none touched the balances.
+	const pendingCount = 20
+	var pendingIDs []int64
+	for i := 0; i < pendingCount; i++ {
+		source, destination := int64(1), int64(2)
+		if i%2 == 1 {
+			source, destination = destination, source
+		}
+		tx, err := txManager.Begin(ctx)
+		if err != nil {
+			t.Fatalf("Begin() error = %v", err)
+		}
+		transaction, err := transactions.Create(ctx, tx, source, destination, "5")
+		if err != nil {
+			tx.Rollback()
+			t.Fatalf("Create() error = %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("Commit() error = %v", err)
+		}
+		pendingIDs = append(pendingIDs, transaction.ID)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	svc := service.NewTransactionService(txManager, transactions, accounts, nopTransferRecorder{}, nil)
+	sweeper := service.NewPendingSweeper(txManager, transactions, accounts, time.Minute, time.Millisecond, pendingCount)
+
+	const perDirection = 20
+	errs := make(chan error, 2*perDirection+2)
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := sweeper.SweepOnce(ctx)
+			if err != nil {
+				errs <- fmt.Errorf("sweep: %w", err)
+			} else if result.Unresolved > 0 {
+				errs <- fmt.Errorf("sweep left %d transactions unresolved", result.Unresolved)
+			}
+		}()
+	}
+	for i := 0; i < perDirection; i++ {
+		for _, direction := range [][2]int64{{1, 2}, {2, 1}} {
+			wg.Add(1)
+			go func(source, destination int64) {
+				defer wg.Done()
+				err := svc.ProcessTransaction(ctx, &models.CreateTransactionRequest{
+					SourceAccountID: source, DestinationAccountID: destination, Amount: "1"})
+				if err != nil {
+					errs <- fmt.Errorf("transfer %d→%d: %w", source, destination, err)
+				}
+			}(direction[0], direction[1])
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	for _, id := range pendingIDs {
+		transaction, err := transactions.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID(%d) error = %v", id, err)
+		}
+		if transaction.Status != models.TransactionStatusFailed || transaction.FailureReason != models.FailureReasonNotApplied {
+			t.Errorf("transaction %d = %s/%s, want failed/not_applied", id, transaction.Status, transaction.FailureReason)
+		}
+	}
+	for _, id := range []int64{1, 2} {
+		account, err := accounts.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID(%d) error = %v", id, err)
+		}
+		if account.Balance != "1000.0000000000" {
+			t.Errorf("account %d balance = %s, want 1000", id, account.Balance)
+		}
+	}
+}
@@ -0,0 +1,547 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"triplea-backend-assignment/database"
+	"triplea-backend-assignment/models"
+)
+
+func TestPostgresConformance(t *testing.T) {
+	store, db := openTestDatabase(t)
+
+	// Round-trip every migration so broken down scripts are caught too.
+	ctx := context.Background()
+	if err := store.MigrateTo(ctx, 0); err != nil {
+		t.Fatalf("failed to roll back migrations: %v", err)
+	}
+	if err := store.MigrateUp(ctx); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	accounts, err := NewPostgresAccountRepository(ctx, store)
+	if err != nil {
+		t.Fatalf("NewPostgresAccountRepository() error = %v", err)
+	}
+	transactions, err := NewPostgresTransactionRepository(ctx, store)
+	if err != nil {
+		t.Fatalf("NewPostgresTransactionRepository() error = %v", err)
+	}
+
+	runConformance(t, func(t *testing.T) repositoryFixture {
+		truncateTables(t, db)
+		return repositoryFixture{
+			txManager:    NewPostgresTxManager(store),
+			accounts:     accounts,
+			transactions: transactions,
+		}
+	})
+}
+
+func TestPostgresPartitionArchive(t *testing.T) {
+	store, db := openTestDatabase(t)
+	truncateTables(t, db)
+	ctx := context.Background()
+
+	old := time.Date(2001, time.March, 10, 12, 0, 0, 0, time.UTC)
+	partition := models.MonthPartition(old)
+	t.Cleanup(func() {
+		db.Exec(`DROP TABLE IF EXISTS ` + partition.Name)
+		truncateTables(t, db)
+	})
+
+	accounts, err := NewPostgresAccountRepository(ctx, store)
+	if err != nil {
+		t.Fatalf("NewPostgresAccountRepository() error = %v", err)
+	}
+	reports, err := NewReportRepository(ctx, store)
+	if err != nil {
+		t.Fatalf("NewReportRepository() error = %v", err)
+	}
+	statements, err := NewStatementRepository(ctx, store)
+	if err != nil {
+		t.Fatalf("NewStatementRepository() error = %v", err)
+	}
+	if err := accounts.Create(ctx, 1, "100"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := accounts.Create(ctx, 2, "0"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	// No partition covers the month yet, so the row lands in the default
+	// partition.
+	_, err = db.Exec(`INSERT INTO transactions (source_account_id, destination_account_id, amount, status, created_at, updated_at)
+					   VALUES (1, 2, 40, 'completed', $1, $1)`, old)
+	if err != nil {
+		t.Fatalf("failed to insert transaction: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE accounts SET balance = CASE account_id WHEN 1 THEN 60 ELSE 40 END`); err != nil {
+		t.Fatalf("failed to update balances: %v", err)
+	}
+
+	partitions := NewPartitionRepository(store)
+	if err := partitions.Create(ctx, partition); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	var inDefault int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM transactions_default`).Scan(&inDefault); err != nil {
+		t.Fatalf("failed to count default partition: %v", err)
+	}
+	if inDefault != 0 {
+		t.Errorf("default partition has %d rows, want them moved to %s", inDefault, partition.Name)
+	}
+	if !hasPartition(t, partitions, partition.Name) {
+		t.Fatalf("List() does not include %s", partition.Name)
+	}
+
+	var written []models.Transaction
+	rows, err := partitions.Archive(ctx, partition, partition.Name+".ndjson.gz",
+		func(transaction *models.Transaction) error {
+			written = append(written, *transaction)
+			return nil
+		},
+		func() error { return nil })
+	if err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+	if rows != 1 || len(written) != 1 || !written[0].CreatedAt.Equal(old) {
+		t.Fatalf("Archive() wrote %d rows %+v, want the transaction created at %v", rows, written, old)
+	}
+	if hasPartition(t, partitions, partition.Name) {
+		t.Errorf("List() still includes %s after archiving", partition.Name)
+	}
+
+	// The archived transfer still explains both balances.
+	report, err := reports.TrialBalance(ctx, "", "", time.Hour)
+	if err != nil {
+		t.Fatalf("TrialBalance() error = %v", err)
+	}
+	if report.Ledger.DiscrepancyCount != 0 {
+		t.Errorf("TrialBalance() discrepancies = %+v, want none", report.Ledger.Discrepancies)
+	}
+
+	noop := func(*models.StatementLine) error { return nil }
+	_, err = statements.Stream(ctx, 1, "2001-03-01", "2001-03-31",
+		func(models.Decimal) error { return nil }, noop)
+	if err == nil || !strings.Contains(err.Error(), "on or after 2001-04-01") {
+		t.Errorf("Stream() error = %v, want the archived range to be refused", err)
+	}
+}
+
+func TestPostgresBusinessDayClose(t *testing.T) {
+	store, db := openTestDatabase(t)
+	truncateTables(t, db)
+	ctx := context.Background()
+
+	accounts, err := NewPostgresAccountRepository(ctx, store)
+	if err != nil {
+		t.Fatalf("NewPostgresAccountRepository() error = %v", err)
+	}
+	days, err := NewBusinessDayRepository(ctx, store)
+	if err != nil {
+		t.Fatalf("NewBusinessDayRepository() error = %v", err)
+	}
+	for _, account := range []struct {
+		id        int64
+		balance   models.Decimal
+		createdAt time.Time
+	}{
+		{1, "100", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{2, "0", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{3, "10", time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC)},
+	} {
+		if err := accounts.Create(ctx, account.id, account.balance); err != nil {
+			t.Fatalf("Create(%d) error = %v", account.id, err)
+		}
+		if _, err := db.Exec(`UPDATE accounts SET created_at = $2 WHERE account_id = $1`, account.id, account.createdAt); err != nil {
+			t.Fatalf("failed to backdate account %d: %v", account.id, err)
+		}
+	}
+	// In ID order: a transfer on the day, one after midnight, and one whose
+	// transaction started before midnight but got a higher ID. Only the
+	// second is backed out.
+	transfers := []struct {
+		source, destination int64
+		amount              string
+		status              string
+		createdAt           time.Time
+	}{
+		{1, 2, "40", "completed", time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)},
+		{2, 1, "5", "completed", time.Date(2024, 3, 11, 0, 30, 0, 0, time.UTC)},
+		{1, 2, "7", "completed", time.Date(2024, 3, 10, 23, 59, 59, 0, time.UTC)},
+		{1, 2, "1000", "failed", time.Date(2024, 3, 11, 1, 0, 0, 0, time.UTC)},
+	}
+	var lastID int64
+	for _, transfer := range transfers {
+		err := db.QueryRow(`INSERT INTO transactions (source_account_id, destination_account_id, amount, status, created_at, updated_at)
+							VALUES ($1, $2, $3, $4, $5, $5) RETURNING id`,
+			transfer.source, transfer.destination, transfer.amount, transfer.status, transfer.createdAt).Scan(&lastID)
+		if err != nil {
+			t.Fatalf("failed to insert transaction: %v", err)
+		}
+	}
+	if _, err := db.Exec(`UPDATE accounts SET balance = CASE account_id WHEN 1 THEN 58 WHEN 2 THEN 42 ELSE 10 END`); err != nil {
+		t.Fatalf("failed to update balances: %v", err)
+	}
+
+	day, err := days.Close(ctx, "2024-03-10")
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if day.HighWaterMark != lastID || day.AccountCount != 2 || day.TotalBalance != "100.0000000000" {
+		t.Errorf("Close() = %+v, want high-water mark %d, 2 accounts and a total of 100", day, lastID)
+	}
+	snapshots, err := days.ListSnapshots(ctx, "2024-03-10")
+	if err != nil {
+		t.Fatalf("ListSnapshots() error = %v", err)
+	}
+	want := []models.BalanceSnapshot{
+		{BusinessDate: "2024-03-10", AccountID: 1, ClosingBalance: "53.0000000000"},
+		{BusinessDate: "2024-03-10", AccountID: 2, ClosingBalance: "47.0000000000"},
+	}
+	if len(snapshots) != len(want) {
+		t.Fatalf("ListSnapshots() = %+v, want %+v", snapshots, want)
+	}
+	for i := range want {
+		if snapshots[i] != want[i] {
+			t.Errorf("snapshot %d = %+v, want %+v", i, snapshots[i], want[i])
+		}
+	}
+
+	if _, err := days.Close(ctx, "2024-03-10"); err == nil || !strings.Contains(err.Error(), "is already closed") {
+		t.Errorf("second Close() error = %v, want the day to be already closed", err)
+	}
+	if got, err := days.GetByDate(ctx, "2024-03-10"); err != nil || got.HighWaterMark != lastID {
+		t.Errorf("GetByDate() = %+v, %v, want the first close", got, err)
+	}
+}
+
+// A transfer that has inserted its transaction but not committed must
+// either be fully in the snapshot or entirely after the high-water mark.
+func TestPostgresBusinessDayClose_WaitsForInFlightTransfers(t *testing.T) {
+	store, db := openTestDatabase(t)
+	truncateTables(t, db)
+	ctx := context.Background()
+
+	accounts, err := NewPostgresAccountRepository(ctx, store)
+	if err != nil {
+		t.Fatalf("NewPostgresAccountRepository() error = %v", err)
+	}
+	days, err := NewBusinessDayRepository(ctx, store)
+	if err != nil {
+		t.Fatalf("NewBusinessDayRepository() error = %v", err)
+	}
+	for _, id := range []int64{1, 2} {
+		if err := accounts.Create(ctx, id, "100"); err != nil {
+			t.Fatalf("Create(%d) error = %v", id, err)
+		}
+	}
+	if _, err := db.Exec(`UPDATE accounts SET created_at = '2024-03-01'`); err != nil {
+		t.Fatalf("failed to backdate accounts: %v", err)
+	}
+
+	transfer, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx() error = %v", err)
+	}
+	defer transfer.Rollback()
+	var transactionID int64
+	err = transfer.QueryRow(`INSERT INTO transactions (source_account_id, destination_account_id, amount, status, created_at, updated_at)
+							 VALUES (1, 2, 30, 'completed', '2024-03-10 18:00:00+00', '2024-03-10 18:00:00+00') RETURNING id`).Scan(&transactionID)
+	if err != nil {
+		t.Fatalf("failed to insert transaction: %v", err)
+	}
+
+	closed := make(chan error, 1)
+	var day *models.BusinessDay
+	go func() {
+		var err error
+		day, err = days.Close(ctx, "2024-03-10")
+		closed <- err
+	}()
+	select {
+	case err := <-closed:
+		t.Fatalf("Close() returned %v while a transfer was in flight, want it to wait", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if _, err := transfer.Exec(`UPDATE accounts SET balance = CASE account_id WHEN 1 THEN 70 ELSE 130 END`); err != nil {
+		t.Fatalf("failed to update balances: %v", err)
+	}
+	if err := transfer.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := <-closed; err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if day.HighWaterMark != transactionID {
+		t.Errorf("high-water mark = %d, want the in-flight transaction %d", day.HighWaterMark, transactionID)
+	}
+	snapshots, err := days.ListSnapshots(ctx, "2024-03-10")
+	if err != nil {
+		t.Fatalf("ListSnapshots() error = %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].ClosingBalance != "70.0000000000" || snapshots[1].ClosingBalance != "130.0000000000" {
+		t.Errorf("ListSnapshots() = %+v, want the in-flight transfer included", snapshots)
+	}
+}
+
+func TestPostgresTrialBalance_DetectsCorruptedBalances(t *testing.T) {
+	store, db := openTestDatabase(t)
+	ctx := context.Background()
+
+	accounts, err := NewPostgresAccountRepository(ctx, store)
+	if err != nil {
+		t.Fatalf("NewPostgresAccountRepository() error = %v", err)
+	}
+	transactions, err := NewPostgresTransactionRepository(ctx, store)
+	if err != nil {
+		t.Fatalf("NewPostgresTransactionRepository() error = %v", err)
+	}
+	reports, err := NewReportRepository(ctx, store)
+	if err != nil {
+		t.Fatalf("NewReportRepository() error = %v", err)
+	}
+	txManager := NewPostgresTxManager(store)
+
+	tests := []struct {
+		name           string
+		corrupt        string
+		wantBalanced   bool
+		wantAccounts   bool
+		wantMismatched []int64
+		wantDiscrepant []int64
+	}{
+		{name: "consistent", wantBalanced: true, wantAccounts: true},
+		{name: "money created", corrupt: `UPDATE accounts SET balance = balance + 1 WHERE account_id = 2`,
+			wantDiscrepant: []int64{2}},
+		// The totals still add up, so only the per-account check catches it.
+		{name: "money moved without a transaction",
+			corrupt:      `UPDATE accounts SET balance = balance + CASE account_id WHEN 1 THEN -5 ELSE 5 END`,
+			wantAccounts: true, wantDiscrepant: []int64{1, 2}},
+		// The balances still match the transfer, so only its legs show it.
+		{name: "credit leg missing", corrupt: `DELETE FROM ledger_entries WHERE amount > 0`,
+			wantAccounts: true, wantMismatched: []int64{1}},
+		{name: "debit does not match credit", corrupt: `UPDATE ledger_entries SET amount = -20 WHERE amount < 0`,
+			wantAccounts: true, wantMismatched: []int64{1}},
+		{name: "failed transfer with legs",
+			corrupt: `INSERT INTO ledger_entries (transaction_id, account_id, amount)
+					  SELECT id, source_account_id, -amount FROM transactions WHERE status = 'failed'`,
+			wantAccounts: true, wantMismatched: []int64{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			truncateTables(t, db)
+			if err := accounts.Create(ctx, 1, "100"); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if err := accounts.Create(ctx, 2, "0"); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			tx, err := txManager.Begin(ctx)
+			if err != nil {
+				t.Fatalf("Begin() error = %v", err)
+			}
+			defer tx.Rollback()
+			transaction, err := transactions.Create(ctx, tx, 1, 2, "30")
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if err := accounts.UpdateBalanceInTx(ctx, tx, 1, "70"); err != nil {
+				t.Fatalf("UpdateBalanceInTx() error = %v", err)
+			}
+			if err := accounts.UpdateBalanceInTx(ctx, tx, 2, "30"); err != nil {
+				t.Fatalf("UpdateBalanceInTx() error = %v", err)
+			}
+			if err := transactions.UpdateStatus(ctx, tx, transaction.ID, models.TransactionStatusCompleted); err != nil {
+				t.Fatalf("UpdateStatus() error = %v", err)
+			}
+			if _, err := transactions.CreateFailed(ctx, tx, 1, 2, "500", models.FailureReasonInsufficientFunds, "70"); err != nil {
+				t.Fatalf("CreateFailed() error = %v", err)
+			}
+			if err := tx.Commit(); err != nil {
+				t.Fatalf("Commit() error = %v", err)
+			}
+			if tt.corrupt != "" {
+				if _, err := db.Exec(tt.corrupt); err != nil {
+					t.Fatalf("failed to corrupt balances: %v", err)
+				}
+			}
+
+			report, err := reports.TrialBalance(ctx, "", "", time.Hour)
+			if err != nil {
+				t.Fatalf("TrialBalance() error = %v", err)
+			}
+			if report.Balanced != tt.wantBalanced || report.Ledger.Balanced != tt.wantBalanced {
+				t.Errorf("balanced = %v, ledger balanced = %v, want %v", report.Balanced, report.Ledger.Balanced, tt.wantBalanced)
+			}
+			if report.Accounts.Balanced != tt.wantAccounts {
+				t.Errorf("accounts balanced = %v, want %v", report.Accounts.Balanced, tt.wantAccounts)
+			}
+			if report.Ledger.TransferredTotal != "30.0000000000" {
+				t.Errorf("transferred total = %s, want 30", report.Ledger.TransferredTotal)
+			}
+			mismatched := report.Ledger.MismatchedTransactions
+			if len(mismatched) != len(tt.wantMismatched) || report.Ledger.MismatchedTransactionCount != int64(len(tt.wantMismatched)) {
+				t.Fatalf("mismatched transactions = %v (count %d), want %v", mismatched, report.Ledger.MismatchedTransactionCount, tt.wantMismatched)
+			}
+			for i := range mismatched {
+				if mismatched[i] != tt.wantMismatched[i] {
+					t.Errorf("mismatched transactions = %v, want %v", mismatched, tt.wantMismatched)
+				}
+			}
+			var got []int64
+			for _, discrepancy := range report.Ledger.Discrepancies {
+				got = append(got, discrepancy.AccountID)
+			}
+			if len(got) != len(tt.wantDiscrepant) || report.Ledger.DiscrepancyCount != int64(len(tt.wantDiscrepant)) {
+				t.Fatalf("discrepancies = %v (count %d), want %v", got, report.Ledger.DiscrepancyCount, tt.wantDiscrepant)
+			}
+			for i := range got {
+				if got[i] != tt.wantDiscrepant[i] {
+					t.Errorf("discrepancies = %v, want %v", got, tt.wantDiscrepant)
+				}
+			}
+		})
+	}
+}
+
+func TestPostgresAPIKeys(t *testing.T) {
+	store, db := openTestDatabase(t)
+	truncateTables(t, db)
+	ctx := context.Background()
+
+	keys, err := NewPostgresAPIKeyRepository(ctx, store)
+	if err != nil {
+		t.Fatalf("NewPostgresAPIKeyRepository() error = %v", err)
+	}
+	hash := strings.Repeat("ab", 32)
+	created, err := keys.Create(ctx, "gateway", "tak_abcdefgh", hash, []string{models.ScopeAccountsRead, models.ScopeTransfersWrite})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.ID == 0 || created.CreatedAt.Location() != time.UTC || created.LastUsedAt != nil || created.RevokedAt != nil {
+		t.Errorf("Create() = %+v, want a new unused key with a UTC creation time", created)
+	}
+
+	usedAt := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+	if err := keys.TouchLastUsed(ctx, created.ID, usedAt); err != nil {
+		t.Fatalf("TouchLastUsed() error = %v", err)
+	}
+	got, err := keys.GetByHash(ctx, hash)
+	if err != nil {
+		t.Fatalf("GetByHash() error = %v", err)
+	}
+	if got.Name != "gateway" || len(got.Scopes) != 2 || got.Scopes[1] != models.ScopeTransfersWrite ||
+		got.LastUsedAt == nil || !got.LastUsedAt.Equal(usedAt) {
+		t.Errorf("GetByHash() = %+v, want the created key used at %v", got, usedAt)
+	}
+	if _, err := keys.GetByHash(ctx, strings.Repeat("cd", 32)); err != ErrAPIKeyNotFound {
+		t.Errorf("GetByHash(unknown) error = %v, want %v", err, ErrAPIKeyNotFound)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := keys.Revoke(ctx, created.ID); err != nil {
+			t.Fatalf("Revoke() #%d error = %v", i+1, err)
+		}
+	}
+	if err := keys.Revoke(ctx, created.ID+1); err != ErrAPIKeyNotFound {
+		t.Errorf("Revoke(unknown) error = %v, want %v", err, ErrAPIKeyNotFound)
+	}
+	list, err := keys.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list) != 1 || list[0].RevokedAt == nil {
+		t.Errorf("List() = %+v, want the revoked key", list)
+	}
+}
+
+func TestPostgresNonces(t *testing.T) {
+	store, db := openTestDatabase(t)
+	truncateTables(t, db)
+	ctx := context.Background()
+
+	nonces, err := NewNonceRepository(ctx, store)
+	if err != nil {
+		t.Fatalf("NewNonceRepository() error = %v", err)
+	}
+	expiresAt := time.Date(2024, 1, 5, 10, 5, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		client, nonce string
+		want          bool
+	}{
+		{"gateway", "0123456789abcdef", true},
+		{"gateway", "0123456789abcdef", false},
+		{"billing", "0123456789abcdef", true},
+	} {
+		got, err := nonces.Remember(ctx, tt.client, tt.nonce, expiresAt)
+		if err != nil {
+			t.Fatalf("Remember(%s, %s) error = %v", tt.client, tt.nonce, err)
+		}
+		if got != tt.want {
+			t.Errorf("Remember(%s, %s) = %v, want %v", tt.client, tt.nonce, got, tt.want)
+		}
+	}
+
+	if deleted, err := nonces.DeleteExpired(ctx, expiresAt); err != nil || deleted != 0 {
+		t.Errorf("DeleteExpired(expiry) = %d, %v, want 0", deleted, err)
+	}
+	if deleted, err := nonces.DeleteExpired(ctx, expiresAt.Add(time.Second)); err != nil || deleted != 2 {
+		t.Errorf("DeleteExpired(after expiry) = %d, %v, want 2", deleted, err)
+	}
+}
+
+func hasPartition(t *testing.T, partitions *PartitionRepository, name string) bool {
+	t.Helper()
+	list, err := partitions.List(context.Background())
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	for _, partition := range list {
+		if partition.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// openTestDatabase connects to the database in TEST_DATABASE_DSN,
+// e.g. "host=localhost user=postgres password=postgres dbname=transfers_test sslmode=disable",
+// and migrates it. Tests truncate every table, so never point it at real
+// data. Sessions use a non-UTC time zone (unless the DSN sets one) to prove
+// that stored and returned timestamps do not depend on it.
+func openTestDatabase(t *testing.T) (*database.Store, *sql.DB) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN not set")
+	}
+	t.Setenv("PGTZ", "America/Sao_Paulo")
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	store := database.NewStore(db)
+	t.Cleanup(func() { store.Close() })
+
+	if err := store.MigrateUp(context.Background()); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return store, db
+}
+
+func truncateTables(t *testing.T, db *sql.DB) {
+	t.Helper()
+	_, err := db.Exec(`TRUNCATE account_balance_snapshots, business_days, transaction_archives,
+					   archived_account_balances, ledger_entries, transactions, accounts, api_keys, request_nonces RESTART IDENTITY`)
+	if err != nil {
+		t.Fatalf("failed to truncate tables: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"triplea-backend-assignment/database"
+)
+
+type PostgresTxManager struct {
+	store *database.Store
+}
+
+func NewPostgresTxManager(store *database.Store) *PostgresTxManager {
+	return &PostgresTxManager{store: store}
+}
+
+func (m *PostgresTxManager) Begin(ctx context.Context) (Tx, error) {
+	tx, err := m.store.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	return &postgresTx{Tx: tx}, nil
+}
+
+type postgresTx struct {
+	*sql.Tx
+}
+
+func unwrapTx(tx Tx) (*sql.Tx, error) {
+	pgTx, ok := tx.(*postgresTx)
+	if !ok {
+		return nil, fmt.Errorf("unsupported transaction type %T", tx)
+	}
+	return pgTx.Tx, nil
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"triplea-backend-assignment/database"
+	"triplea-backend-assignment/models"
+)
+
+const reportListLimit = 100
+
+// ledgerBalancesQuery derives every account's expected balance from its
+// initial balance, its archived transfers and its completed transfers.
+const ledgerBalancesQuery = `SELECT a.account_id, a.balance,
+		a.initial_balance + COALESCE(ab.net_amount, 0) + COALESCE(c.total, 0) - COALESCE(d.total, 0) AS expected_balance
+	FROM accounts a
+	LEFT JOIN archived_account_balances ab ON ab.account_id = a.account_id
+	LEFT JOIN (SELECT destination_account_id AS account_id, SUM(amount) AS total
+			   FROM transactions WHERE status = 'completed' GROUP BY destination_account_id) c
+		ON c.account_id = a.account_id
+	LEFT JOIN (SELECT source_account_id AS account_id, SUM(amount) AS total
+			   FROM transactions WHERE status = 'completed' GROUP BY source_account_id) d
+		ON d.account_id = a.account_id`
+
+// mismatchedTransactionsQuery finds the transactions in the window whose
+// ledger entries do not match them: a completed transfer needs exactly a
+// debit of its amount on the source and a credit of its amount on the
+// destination, and any other transfer must have no entries at all.
+const mismatchedTransactionsQuery = `SELECT t.id, COUNT(*) OVER ()
+	FROM transactions t
+	CROSS JOIN LATERAL (
+		SELECT COUNT(*) AS entries,
+			COALESCE(SUM(-e.amount) FILTER (WHERE e.account_id = t.source_account_id AND e.amount < 0), 0) AS debited,
+			COALESCE(SUM(e.amount) FILTER (WHERE e.account_id = t.destination_account_id AND e.amount > 0), 0) AS credited
+		FROM ledger_entries e WHERE e.transaction_id = t.id
+	) legs
+	WHERE ($1::date IS NULL OR t.created_at >= $1::date::timestamp AT TIME ZONE 'UTC')
+	  AND ($2::date IS NULL OR t.created_at < ($2::date + 1)::timestamp AT TIME ZONE 'UTC')
+	  AND CASE WHEN t.status = $3 THEN legs.entries <> 2 OR legs.debited <> t.amount OR legs.credited <> t.amount
+			   ELSE legs.entries > 0 END
+	ORDER BY t.id LIMIT $4`
+
+type ReportRepository struct {
+	store *database.Store
+
+	accountTotals          *database.Statement
+	transactionTotals      *database.Statement
+	completedTotals        *database.Statement
+	mismatchedTransactions *database.Statement
+	discrepancies          *database.Statement
+	stuckPending           *database.Statement
+}
+
+func NewReportRepository(ctx context.Context, store *database.Store) (*ReportRepository, error) {
+	r := &ReportRepository{store: store}
+	err := prepareStatements(ctx, store, []statementSpec{
+		{&r.accountTotals, "ReportRepository.AccountTotals",
+			`SELECT CURRENT_TIMESTAMP, COUNT(*), COALESCE(SUM(balance), 0), COALESCE(SUM(initial_balance), 0),
+				COALESCE(SUM(balance), 0) = COALESCE(SUM(initial_balance), 0)
+			 FROM accounts`},
+		{&r.transactionTotals, "ReportRepository.TransactionTotals",
+			`SELECT status, COUNT(*), COALESCE(SUM(amount), 0)
+			 FROM transactions
+			 WHERE ($1::date IS NULL OR created_at >= $1::date::timestamp AT TIME ZONE 'UTC')
+			   AND ($2::date IS NULL OR created_at < ($2::date + 1)::timestamp AT TIME ZONE 'UTC')
+			 GROUP BY status ORDER BY status::text`},
+		{&r.completedTotals, "ReportRepository.CompletedTotals",
+			`SELECT COALESCE(SUM(amount), 0)
+			 FROM transactions
+			 WHERE status = $3
+			   AND ($1::date IS NULL OR created_at >= $1::date::timestamp AT TIME ZONE 'UTC')
+			   AND ($2::date IS NULL OR created_at < ($2::date + 1)::timestamp AT TIME ZONE 'UTC')`},
+		{&r.mismatchedTransactions, "ReportRepository.MismatchedTransactions", mismatchedTransactionsQuery},
+		{&r.discrepancies, "ReportRepository.Discrepancies",
+			`SELECT account_id, balance, expected_balance, COUNT(*) OVER ()
+			 FROM (` + ledgerBalancesQuery + `) ledger
+			 WHERE balance <> expected_balance
+			 ORDER BY account_id LIMIT $1`},
+		{&r.stuckPending, "ReportRepository.StuckPending",
+			`SELECT ` + transactionColumns + `, COUNT(*) OVER ()
+			 FROM transactions
+			 WHERE status = $1 AND created_at < CURRENT_TIMESTAMP - make_interval(secs => $2)
+			 ORDER BY created_at LIMIT $3`},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TrialBalance gathers every figure of the report from one consistent
+// snapshot so that account totals and transaction totals can be compared.
+// Empty from/to leave the transaction window open on that side.
+func (r *ReportRepository) TrialBalance(ctx context.Context, from, to string, pendingThreshold time.Duration) (*models.TrialBalanceReport, error) {
+	tx, err := r.store.DB().BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	report := &models.TrialBalanceReport{From: from, To: to}
+	windowFrom := sql.NullString{String: from, Valid: from != ""}
+	windowTo := sql.NullString{String: to, Valid: to != ""}
+
+	err = r.accountTotals.InTx(ctx, tx).QueryRowContext(ctx).Scan(&report.GeneratedAt, &report.Accounts.Count, &report.Accounts.TotalBalance,
+		&report.Accounts.TotalInitialBalance, &report.Accounts.Balanced)
+	if err != nil {
+		return nil, fmt.Errorf("failed to total account balances: %w", err)
+	}
+	report.GeneratedAt = report.GeneratedAt.UTC()
+
+	rows, err := r.transactionTotals.InTx(ctx, tx).QueryContext(ctx, windowFrom, windowTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to total transactions by status: %w", err)
+	}
+	report.Transactions = []models.TransactionStatusTotals{}
+	for rows.Next() {
+		var totals models.TransactionStatusTotals
+		if err := rows.Scan(&totals.Status, &totals.Count, &totals.TotalAmount); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan transaction totals: %w", err)
+		}
+		report.Transactions = append(report.Transactions, totals)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to total transactions by status: %w", err)
+	}
+
+	err = r.completedTotals.InTx(ctx, tx).QueryRowContext(ctx, windowFrom, windowTo, models.TransactionStatusCompleted).
+		Scan(&report.Ledger.TransferredTotal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to total completed transfers: %w", err)
+	}
+
+	rows, err = r.mismatchedTransactions.InTx(ctx, tx).QueryContext(ctx, windowFrom, windowTo,
+		models.TransactionStatusCompleted, reportListLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check transaction ledger entries: %w", err)
+	}
+	report.Ledger.MismatchedTransactions = []int64{}
+	for rows.Next() {
+		var transactionID int64
+		if err := rows.Scan(&transactionID, &report.Ledger.MismatchedTransactionCount); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan mismatched transaction: %w", err)
+		}
+		report.Ledger.MismatchedTransactions = append(report.Ledger.MismatchedTransactions, transactionID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to check transaction ledger entries: %w", err)
+	}
+
+	rows, err = r.discrepancies.InTx(ctx, tx).QueryContext(ctx, reportListLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check account ledgers: %w", err)
+	}
+	report.Ledger.Discrepancies = []models.AccountDiscrepancy{}
+	for rows.Next() {
+		var discrepancy models.AccountDiscrepancy
+		err := rows.Scan(&discrepancy.AccountID, &discrepancy.Balance, &discrepancy.ExpectedBalance,
+			&report.Ledger.DiscrepancyCount)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan ledger discrepancy: %w", err)
+		}
+		report.Ledger.Discrepancies = append(report.Ledger.Discrepancies, discrepancy)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to check account ledgers: %w", err)
+	}
+
+	report.StuckPending.Threshold = pendingThreshold.String()
+	rows, err = r.stuckPending.InTx(ctx, tx).QueryContext(ctx, models.TransactionStatusPending, pendingThreshold.Seconds(), reportListLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stuck pending transactions: %w", err)
+	}
+	report.StuckPending.Transactions = []models.Transaction{}
+	for rows.Next() {
+		var transaction models.Transaction
+		if err := scanTransaction(rows, &transaction, &report.StuckPending.Count); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan pending transaction: %w", err)
+		}
+		report.StuckPending.Transactions = append(report.StuckPending.Transactions, transaction)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find stuck pending transactions: %w", err)
+	}
+
+	// Each completed transfer must be backed by both of its legs, and each
+	// balance must equal its initial balance plus its completed credits
+	// minus its completed debits.
+	report.Ledger.Balanced = report.Ledger.MismatchedTransactionCount == 0 && report.Ledger.DiscrepancyCount == 0
+	report.Balanced = report.Accounts.Balanced && report.Ledger.Balanced && report.StuckPending.Count == 0
+
+	return report, nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"triplea-backend-assignment/models"
+)
+
+var (
+	ErrAccountNotFound     = errors.New("account not found")
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrAPIKeyNotFound      = errors.New("API key not found")
+)
+
+// Errors for writes rejected by the schema's integrity constraints. The
+// in-memory store enforces the same constraints and returns the same errors.
+var (
+	ErrAccountExists     = errors.New("account already exists")
+	ErrInsufficientFunds = errors.New("insufficient balance")
+	ErrInvalidAmount     = errors.New("amount must be greater than zero")
+	ErrSameAccount       = errors.New("source and destination accounts must be different")
+	ErrInvalidStatus     = errors.New("invalid transaction status")
+)
+
+// Tx is a unit of work. Repository calls that receive the same Tx are
+// committed or rolled back together, and rows read "with lock" stay locked
+// until the Tx ends. Rollback after Commit only returns sql.ErrTxDone, so it
+// can be deferred right after Begin. A Tx is rolled back when the context
+// passed to Begin is done, and Commit then fails.
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+type TxManager interface {
+	Begin(ctx context.Context) (Tx, error)
+}
+
+type AccountRepository interface {
+	Create(ctx context.Context, accountID int64, balance models.Decimal) error
+	GetByID(ctx context.Context, accountID int64) (*models.Account, error)
+	UpdateBalance(ctx context.Context, accountID int64, newBalance models.Decimal) error
+	Exists(ctx context.Context, accountID int64) (bool, error)
+	GetByIDWithLock(ctx context.Context, tx Tx, accountID int64) (*models.Account, error)
+	UpdateBalanceInTx(ctx context.Context, tx Tx, accountID int64, newBalance models.Decimal) error
+}
+
+type TransactionRepository interface {
+	Create(ctx context.Context, tx Tx, sourceAccountID, destinationAccountID int64, amount models.Decimal) (*models.Transaction, error)
+	CreateFailed(
+		ctx context.Context,
+		tx Tx,
+		sourceAccountID, destinationAccountID int64,
+		amount models.Decimal,
+		reason string,
+		balanceAtDecision models.Decimal,
+	) (*models.Transaction, error)
+	UpdateStatus(ctx context.Context, tx Tx, transactionID int64, status string) error
+	MarkFailed(ctx context.Context, tx Tx, transactionID int64, reason string) error
+	GetByID(ctx context.Context, transactionID int64) (*models.Transaction, error)
+	GetByIDWithLock(ctx context.Context, tx Tx, transactionID int64) (*models.Transaction, error)
+	ListByAccount(ctx context.Context, accountID int64, status string, beforeID int64, limit int) ([]models.Transaction, error)
+	ListPendingOlderThan(ctx context.Context, age time.Duration, limit int) ([]models.Transaction, error)
+	LedgerEvidence(ctx context.Context, tx Tx, transaction *models.Transaction) (applied bool, notApplied bool, err error)
+}
+
+// APIKeyRepository stores API keys by the SHA-256 of the key; the key itself
+// is never stored.
+type APIKeyRepository interface {
+	Create(ctx context.Context, name, prefix, keyHash string, scopes []string) (*models.APIKey, error)
+	GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	List(ctx context.Context) ([]models.APIKey, error)
+	Revoke(ctx context.Context, id int64) error
+	TouchLastUsed(ctx context.Context, id int64, at time.Time) error
+}
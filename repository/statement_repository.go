@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"triplea-backend-assignment/database"
+	"triplea-backend-assignment/models"
+)
+
+type StatementRepository struct {
+	store *database.Store
+
+	archivedBefore *database.Statement
+	opening        *database.Statement
+	lines          *database.Statement
+}
+
+func NewStatementRepository(ctx context.Context, store *database.Store) (*StatementRepository, error) {
+	r := &StatementRepository{store: store}
+	err := prepareStatements(ctx, store, []statementSpec{
+		{&r.archivedBefore, "StatementRepository.ArchivedBefore",
+			`SELECT MAX(range_end) FROM transaction_archives`},
+		{&r.opening, "StatementRepository.Opening",
+			`SELECT a.balance
+				- COALESCE((SELECT SUM(t.amount) FROM transactions t
+							WHERE t.destination_account_id = a.account_id AND t.status = $3 AND t.created_at >= $2::date::timestamp AT TIME ZONE 'UTC'), 0)
+				+ COALESCE((SELECT SUM(t.amount) FROM transactions t
+							WHERE t.source_account_id = a.account_id AND t.status = $3 AND t.created_at >= $2::date::timestamp AT TIME ZONE 'UTC'), 0)
+			 FROM accounts a WHERE a.account_id = $1`},
+		{&r.lines, "StatementRepository.Lines",
+			`SELECT id, created_at,
+				CASE WHEN source_account_id = $1 THEN destination_account_id ELSE source_account_id END,
+				CASE WHEN source_account_id = $1 THEN $6 ELSE $7 END,
+				amount,
+				$4::numeric + SUM(CASE WHEN source_account_id = $1 THEN -amount ELSE amount END)
+					OVER (ORDER BY created_at, id)
+			 FROM transactions
+			 WHERE (source_account_id = $1 OR destination_account_id = $1)
+			   AND status = $5 AND created_at >= $2::date::timestamp AT TIME ZONE 'UTC'
+			   AND created_at < ($3::date + 1)::timestamp AT TIME ZONE 'UTC'
+			 ORDER BY created_at, id`},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Stream reads the opening balance and the completed transactions of an
+// account between two dates (inclusive) from one consistent snapshot and
+// hands them to the callbacks one row at a time. It returns the closing
+// balance once every row has been delivered.
+func (r *StatementRepository) Stream(
+	ctx context.Context,
+	accountID int64,
+	from, to string,
+	onOpening func(opening models.Decimal) error,
+	onLine func(line *models.StatementLine) error,
+) (models.Decimal, error) {
+	db := r.store.ReadDB(ctx)
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The opening balance is worked back from the current balance, which
+	// needs every transaction since from.
+	var archivedUntil sql.NullTime
+	if err := r.archivedBefore.InTxOn(ctx, db, tx).QueryRowContext(ctx).Scan(&archivedUntil); err != nil {
+		return "", fmt.Errorf("failed to get archived range: %w", err)
+	}
+	if fromDate, err := time.Parse(models.BusinessDateLayout, from); err == nil && archivedUntil.Valid && fromDate.Before(archivedUntil.Time) {
+		return "", fmt.Errorf("from must be on or after %s, earlier transactions are archived",
+			archivedUntil.Time.UTC().Format(models.BusinessDateLayout))
+	}
+
+	var opening models.Decimal
+	err = r.opening.InTxOn(ctx, db, tx).QueryRowContext(ctx, accountID, from, models.TransactionStatusCompleted).Scan(&opening)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("account not found")
+		}
+		return "", fmt.Errorf("failed to get opening balance: %w", err)
+	}
+	if err := onOpening(opening); err != nil {
+		return "", err
+	}
+
+	rows, err := r.lines.InTxOn(ctx, db, tx).QueryContext(ctx, accountID, from, to, opening, models.TransactionStatusCompleted,
+		models.StatementDirectionDebit, models.StatementDirectionCredit)
+	if err != nil {
+		return "", fmt.Errorf("failed to query statement lines: %w", err)
+	}
+	defer rows.Close()
+
+	closing := opening
+	for rows.Next() {
+		line := &models.StatementLine{}
+		err := rows.Scan(&line.TransactionID, &line.CreatedAt, &line.CounterpartyAccountID,
+			&line.Direction, &line.Amount, &line.RunningBalance)
+		if err != nil {
+			return "", fmt.Errorf("failed to scan statement line: %w", err)
+		}
+		line.CreatedAt = line.CreatedAt.UTC()
+		if err := onLine(line); err != nil {
+			return "", err
+		}
+		closing = line.RunningBalance
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("failed to read statement lines: %w", err)
+	}
+
+	return closing, nil
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+
+	"triplea-backend-assignment/database"
+)
+
+// statementSpec names a query for the statement cache of a repository.
+// Names are "<Repository>.<Method>" so query statistics point at the code
+// that ran them.
+type statementSpec struct {
+	target **database.Statement
+	name   string
+	query  string
+}
+
+func prepareStatements(ctx context.Context, store *database.Store, specs []statementSpec) error {
+	for _, spec := range specs {
+		stmt, err := store.Prepare(ctx, spec.name, spec.query)
+		if err != nil {
+			return err
+		}
+		*spec.target = stmt
+	}
+	return nil
+}
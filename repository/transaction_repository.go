@@ -1,56 +1,272 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"triplea-backend-assignment/database"
 	"triplea-backend-assignment/models"
 )
 
-type TransactionRepository struct{}
+const transactionColumns = `id, source_account_id, destination_account_id, amount, status,
+	COALESCE(failure_reason, ''), balance_at_decision, created_at, updated_at`
 
-func NewTransactionRepository() *TransactionRepository {
-	return &TransactionRepository{}
+// listByAccountQuery returns an account's transactions in either direction,
+// newest first. $3 is an exclusive ID cursor; zero starts from the newest. An
+// empty status matches every status.
+const listByAccountQuery = `SELECT ` + transactionColumns + `
+	FROM transactions
+	WHERE (source_account_id = $1 OR destination_account_id = $1)
+		AND ($2 = '' OR status = NULLIF($2, '')::transaction_status)
+		AND ($3 = 0 OR id < $3)
+	ORDER BY id DESC LIMIT $4`
+
+const ledgerEvidenceQuery = `WITH ledger AS (
+				SELECT a.account_id, a.balance,
+					a.initial_balance
+					+ COALESCE((SELECT ab.net_amount FROM archived_account_balances ab WHERE ab.account_id = a.account_id), 0)
+					+ COALESCE((SELECT SUM(t.amount) FROM transactions t
+								WHERE t.destination_account_id = a.account_id AND t.status = $4), 0)
+					- COALESCE((SELECT SUM(t.amount) FROM transactions t
+								WHERE t.source_account_id = a.account_id AND t.status = $4), 0) AS expected_balance
+				FROM accounts a WHERE a.account_id IN ($1, $2)
+			  )
+			  SELECT COALESCE(bool_and(CASE WHEN account_id = $1 THEN balance = expected_balance - $3::numeric
+											ELSE balance = expected_balance + $3::numeric END), false),
+					 COALESCE(bool_and(balance = expected_balance), false)
+			  FROM ledger`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTransaction reads transactionColumns plus any extra columns, and
+// normalizes the timestamps to UTC whatever the session time zone is.
+func scanTransaction(row rowScanner, transaction *models.Transaction, extra ...interface{}) error {
+	dest := []interface{}{&transaction.ID, &transaction.SourceAccountID, &transaction.DestinationAccountID,
+		&transaction.Amount, &transaction.Status, &transaction.FailureReason, &transaction.BalanceAtDecision,
+		&transaction.CreatedAt, &transaction.UpdatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	transaction.CreatedAt = transaction.CreatedAt.UTC()
+	transaction.UpdatedAt = transaction.UpdatedAt.UTC()
+	return nil
+}
+
+type PostgresTransactionRepository struct {
+	store *database.Store
+
+	create               *database.Statement
+	createFailed         *database.Statement
+	updateStatus         *database.Statement
+	complete             *database.Statement
+	markFailed           *database.Statement
+	getByID              *database.Statement
+	getByIDWithLock      *database.Statement
+	listByAccount        *database.Statement
+	listPendingOlderThan *database.Statement
+	ledgerEvidence       *database.Statement
 }
 
-func (r *TransactionRepository) Create(tx *sql.Tx, sourceAccountID, destinationAccountID int64, amount models.Decimal) (*models.Transaction, error) {
-	query := `INSERT INTO transactions (source_account_id, destination_account_id, amount, status)
-			  VALUES ($1, $2, $3, $4)
-			  RETURNING id, source_account_id, destination_account_id, amount, status, created_at, updated_at`
-	
+// NewPostgresTransactionRepository prepares every query of the repository.
+func NewPostgresTransactionRepository(ctx context.Context, store *database.Store) (*PostgresTransactionRepository, error) {
+	r := &PostgresTransactionRepository{store: store}
+	err := prepareStatements(ctx, store, []statementSpec{
+		{&r.create, "TransactionRepository.Create",
+			`INSERT INTO transactions (source_account_id, destination_account_id, amount, status)
+			 VALUES ($1, $2, $3, $4)
+			 RETURNING ` + transactionColumns},
+		{&r.createFailed, "TransactionRepository.CreateFailed",
+			`INSERT INTO transactions (source_account_id, destination_account_id, amount, status, failure_reason, balance_at_decision)
+			 VALUES ($1, $2, $3, $4, $5, $6)
+			 RETURNING ` + transactionColumns},
+		{&r.updateStatus, "TransactionRepository.UpdateStatus",
+			`UPDATE transactions SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`},
+		{&r.complete, "TransactionRepository.Complete",
+			`WITH completed AS (
+				UPDATE transactions SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2
+				RETURNING id, source_account_id, destination_account_id, amount
+			 )
+			 INSERT INTO ledger_entries (transaction_id, account_id, amount)
+			 SELECT id, source_account_id, -amount FROM completed
+			 UNION ALL
+			 SELECT id, destination_account_id, amount FROM completed`},
+		{&r.markFailed, "TransactionRepository.MarkFailed",
+			`UPDATE transactions SET status = $1, failure_reason = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`},
+		{&r.getByID, "TransactionRepository.GetByID",
+			`SELECT ` + transactionColumns + ` FROM transactions WHERE id = $1`},
+		{&r.getByIDWithLock, "TransactionRepository.GetByIDWithLock",
+			`SELECT ` + transactionColumns + ` FROM transactions WHERE id = $1 FOR UPDATE`},
+		{&r.listByAccount, "TransactionRepository.ListByAccount", listByAccountQuery},
+		{&r.listPendingOlderThan, "TransactionRepository.ListPendingOlderThan",
+			`SELECT ` + transactionColumns + `
+			 FROM transactions
+			 WHERE status = $1 AND created_at < CURRENT_TIMESTAMP - make_interval(secs => $2)
+			 ORDER BY created_at LIMIT $3`},
+		{&r.ledgerEvidence, "TransactionRepository.LedgerEvidence", ledgerEvidenceQuery},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *PostgresTransactionRepository) Create(ctx context.Context, tx Tx, sourceAccountID, destinationAccountID int64, amount models.Decimal) (*models.Transaction, error) {
+	sqlTx, err := unwrapTx(tx)
+	if err != nil {
+		return nil, err
+	}
 	transaction := &models.Transaction{}
-	err := tx.QueryRow(query, sourceAccountID, destinationAccountID, amount, models.TransactionStatusPending).
-		Scan(&transaction.ID, &transaction.SourceAccountID, &transaction.DestinationAccountID,
-			&transaction.Amount, &transaction.Status, &transaction.CreatedAt, &transaction.UpdatedAt)
+	err = scanTransaction(r.create.InTx(ctx, sqlTx).QueryRowContext(ctx, sourceAccountID, destinationAccountID, amount, models.TransactionStatusPending), transaction)
 	if err != nil {
+		if domainErr := constraintError(err); domainErr != nil {
+			return nil, domainErr
+		}
 		return nil, fmt.Errorf("failed to create transaction: %w", err)
 	}
 	return transaction, nil
 }
 
-func (r *TransactionRepository) UpdateStatus(tx *sql.Tx, transactionID int64, status string) error {
-	query := `UPDATE transactions SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
-	_, err := tx.Exec(query, status, transactionID)
+// CreateFailed records a transfer attempt that was rejected after request
+// validation, together with the source balance the decision was based on.
+func (r *PostgresTransactionRepository) CreateFailed(
+	ctx context.Context,
+	tx Tx,
+	sourceAccountID, destinationAccountID int64,
+	amount models.Decimal,
+	reason string,
+	balanceAtDecision models.Decimal,
+) (*models.Transaction, error) {
+	sqlTx, err := unwrapTx(tx)
 	if err != nil {
+		return nil, err
+	}
+	transaction := &models.Transaction{}
+	err = scanTransaction(r.createFailed.InTx(ctx, sqlTx).QueryRowContext(ctx, sourceAccountID, destinationAccountID, amount,
+		models.TransactionStatusFailed, reason, balanceAtDecision), transaction)
+	if err != nil {
+		if domainErr := constraintError(err); domainErr != nil {
+			return nil, domainErr
+		}
+		return nil, fmt.Errorf("failed to create failed transaction: %w", err)
+	}
+	return transaction, nil
+}
+
+// UpdateStatus sets the status of a transaction. Completing it also records
+// its debit and credit legs in ledger_entries.
+func (r *PostgresTransactionRepository) UpdateStatus(ctx context.Context, tx Tx, transactionID int64, status string) error {
+	sqlTx, err := unwrapTx(tx)
+	if err != nil {
+		return err
+	}
+	stmt := r.updateStatus
+	if status == models.TransactionStatusCompleted {
+		stmt = r.complete
+	}
+	_, err = stmt.InTx(ctx, sqlTx).ExecContext(ctx, status, transactionID)
+	if err != nil {
+		if domainErr := constraintError(err); domainErr != nil {
+			return domainErr
+		}
 		return fmt.Errorf("failed to update transaction status: %w", err)
 	}
 	return nil
 }
 
-func (r *TransactionRepository) GetByID(transactionID int64) (*models.Transaction, error) {
-	query := `SELECT id, source_account_id, destination_account_id, amount, status, created_at, updated_at
-			  FROM transactions WHERE id = $1`
+func (r *PostgresTransactionRepository) MarkFailed(ctx context.Context, tx Tx, transactionID int64, reason string) error {
+	sqlTx, err := unwrapTx(tx)
+	if err != nil {
+		return err
+	}
+	_, err = r.markFailed.InTx(ctx, sqlTx).ExecContext(ctx, models.TransactionStatusFailed, reason, transactionID)
+	if err != nil {
+		if domainErr := constraintError(err); domainErr != nil {
+			return domainErr
+		}
+		return fmt.Errorf("failed to mark transaction failed: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresTransactionRepository) GetByID(ctx context.Context, transactionID int64) (*models.Transaction, error) {
 	transaction := &models.Transaction{}
-	err := database.DB.QueryRow(query, transactionID).
-		Scan(&transaction.ID, &transaction.SourceAccountID, &transaction.DestinationAccountID,
-			&transaction.Amount, &transaction.Status, &transaction.CreatedAt, &transaction.UpdatedAt)
+	err := scanTransaction(r.getByID.On(ctx, r.store.ReadDB(ctx)).QueryRowContext(ctx, transactionID), transaction)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("transaction not found")
+			return nil, ErrTransactionNotFound
 		}
 		return nil, fmt.Errorf("failed to get transaction: %w", err)
 	}
 	return transaction, nil
 }
 
+func (r *PostgresTransactionRepository) GetByIDWithLock(ctx context.Context, tx Tx, transactionID int64) (*models.Transaction, error) {
+	sqlTx, err := unwrapTx(tx)
+	if err != nil {
+		return nil, err
+	}
+	transaction := &models.Transaction{}
+	err = scanTransaction(r.getByIDWithLock.InTx(ctx, sqlTx).QueryRowContext(ctx, transactionID), transaction)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	return transaction, nil
+}
+
+// ListByAccount returns the account's transactions in either direction,
+// newest first. beforeID is an exclusive cursor; zero starts from the newest.
+// An empty status matches every status.
+func (r *PostgresTransactionRepository) ListByAccount(ctx context.Context, accountID int64, status string, beforeID int64, limit int) ([]models.Transaction, error) {
+	return r.list(ctx, r.listByAccount.On(ctx, r.store.ReadDB(ctx)), accountID, status, beforeID, limit)
+}
+
+func (r *PostgresTransactionRepository) ListPendingOlderThan(ctx context.Context, age time.Duration, limit int) ([]models.Transaction, error) {
+	return r.list(ctx, r.listPendingOlderThan.On(ctx, r.store.DB()), models.TransactionStatusPending, age.Seconds(), limit)
+}
+
+func (r *PostgresTransactionRepository) list(ctx context.Context, stmt *database.BoundStatement, args ...interface{}) ([]models.Transaction, error) {
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []models.Transaction{}
+	for rows.Next() {
+		var transaction models.Transaction
+		if err := scanTransaction(rows, &transaction); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, transaction)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+	return transactions, nil
+}
+
+// LedgerEvidence compares the balances of both accounts of a pending
+// transaction with the balances their completed transactions explain.
+// applied means the balances already include the transfer, notApplied means
+// they exclude it; both false means the ledger cannot tell. Both accounts
+// must be locked by tx.
+func (r *PostgresTransactionRepository) LedgerEvidence(ctx context.Context, tx Tx, transaction *models.Transaction) (applied bool, notApplied bool, err error) {
+	sqlTx, err := unwrapTx(tx)
+	if err != nil {
+		return false, false, err
+	}
+
+	err = r.ledgerEvidence.InTx(ctx, sqlTx).QueryRowContext(ctx, transaction.SourceAccountID, transaction.DestinationAccountID,
+		transaction.Amount, models.TransactionStatusCompleted).Scan(&applied, &notApplied)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to read ledger evidence: %w", err)
+	}
+	return applied, notApplied, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// backgroundWorkers runs the loops that live alongside the HTTP server, such
+// as the pending sweeper, so shutdown can stop them and wait for them.
+type backgroundWorkers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newBackgroundWorkers() *backgroundWorkers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &backgroundWorkers{ctx: ctx, cancel: cancel}
+}
+
+func (w *backgroundWorkers) Go(run func(ctx context.Context)) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		run(w.ctx)
+	}()
+}
+
+// Stop cancels the workers and waits for them to return, or for ctx to be
+// done. The work they are in the middle of is rolled back, and is picked up
+// again by the next process.
+func (w *backgroundWorkers) Stop(ctx context.Context) error {
+	w.cancel()
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background workers did not stop: %w", ctx.Err())
+	}
+}
+
+// shutdownPlan says how serve stops. notReady is called first; the server
+// keeps serving for delay so load balancers see the readiness probe fail
+// before connections are refused.
+type shutdownPlan struct {
+	notReady func()
+	delay    time.Duration
+	grace    time.Duration
+}
+
+// serve runs server on listener until ctx is done. It then stops accepting
+// connections, waits up to grace for in-flight requests to finish, and
+// stops the background workers within what is left of grace. Requests still
+// running after grace have their connections closed.
+func serve(ctx context.Context, server *http.Server, listener net.Listener, plan shutdownPlan, workers *backgroundWorkers) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		// The server failed on its own; nothing is left to drain.
+		workers.Stop(context.Background())
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	if plan.notReady != nil {
+		plan.notReady()
+	}
+	if plan.delay > 0 {
+		slog.Info("shutting down, still serving while load balancers catch up", "delay", plan.delay.String())
+		time.Sleep(plan.delay)
+	}
+
+	slog.Info("shutting down, waiting for in-flight requests", "grace_period", plan.grace.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), plan.grace)
+	defer cancel()
+
+	var shutdownErr error
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		server.Close()
+		shutdownErr = fmt.Errorf("in-flight requests did not finish: %w", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		shutdownErr = errors.Join(shutdownErr, fmt.Errorf("server failed: %w", err))
+	}
+	if err := workers.Stop(shutdownCtx); err != nil {
+		shutdownErr = errors.Join(shutdownErr, err)
+	}
+	return shutdownErr
+}
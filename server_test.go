@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	tests := []struct {
+		name        string
+		grace       time.Duration
+		release     bool
+		wantErr     bool
+		wantDrained bool
+	}{
+		{name: "request finishes within grace period", grace: 5 * time.Second, release: true, wantDrained: true},
+		{name: "grace period expires", grace: 50 * time.Millisecond, release: false, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			defer close(release)
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				select {
+				case <-release:
+				case <-r.Context().Done():
+					return
+				}
+				w.Write([]byte("done"))
+			})
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("net.Listen() error = %v", err)
+			}
+			workers := newBackgroundWorkers()
+			workerStopped := make(chan struct{})
+			workers.Go(func(ctx context.Context) {
+				<-ctx.Done()
+				close(workerStopped)
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			notReady := make(chan struct{})
+			plan := shutdownPlan{notReady: func() { close(notReady) }, grace: tt.grace}
+			served := make(chan error, 1)
+			go func() {
+				served <- serve(ctx, &http.Server{Handler: handler}, listener, plan, workers)
+			}()
+
+			url := "http://" + listener.Addr().String()
+			response := make(chan string, 1)
+			go func() {
+				resp, err := http.Get(url)
+				if err != nil {
+					response <- ""
+					return
+				}
+				defer resp.Body.Close()
+				body, _ := io.ReadAll(resp.Body)
+				response <- string(body)
+			}()
+			<-started
+
+			cancel()
+			// Shutdown closes the listener before it waits for requests.
+			deadline := time.Now().Add(time.Second)
+			for {
+				conn, err := net.Dial("tcp", listener.Addr().String())
+				if err != nil {
+					break
+				}
+				conn.Close()
+				if time.Now().After(deadline) {
+					t.Fatal("server still accepts connections after shutdown started")
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			if tt.release {
+				release <- struct{}{}
+			}
+
+			err = <-served
+			select {
+			case <-notReady:
+			default:
+				t.Error("serve() did not report the server as not ready")
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("serve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := <-response; (got == "done") != tt.wantDrained {
+				t.Errorf("in-flight response = %q, want drained %v", got, tt.wantDrained)
+			}
+			select {
+			case <-workerStopped:
+			default:
+				t.Error("serve() returned before the background workers stopped")
+			}
+		})
+	}
+}
+
+func TestBackgroundWorkersStopTimesOut(t *testing.T) {
+	workers := newBackgroundWorkers()
+	block := make(chan struct{})
+	defer close(block)
+	workers.Go(func(ctx context.Context) {
+		<-block
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := workers.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Stop() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
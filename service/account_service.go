@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 
 	"triplea-backend-assignment/models"
@@ -8,21 +9,21 @@ import (
 )
 
 type AccountService struct {
-	accountRepo *repository.AccountRepository
+	accountRepo repository.AccountRepository
 }
 
-func NewAccountService(accountRepo *repository.AccountRepository) *AccountService {
+func NewAccountService(accountRepo repository.AccountRepository) *AccountService {
 	return &AccountService{
 		accountRepo: accountRepo,
 	}
 }
 
-func (s *AccountService) CreateAccount(req *models.CreateAccountRequest) error {
+func (s *AccountService) CreateAccount(ctx context.Context, req *models.CreateAccountRequest) error {
 	if err := req.Validate(); err != nil {
 		return fmt.Errorf("validation error: %w", err)
 	}
 
-	exists, err := s.accountRepo.Exists(req.AccountID)
+	exists, err := s.accountRepo.Exists(ctx, req.AccountID)
 	if err != nil {
 		return fmt.Errorf("failed to check account existence: %w", err)
 	}
@@ -31,19 +32,19 @@ func (s *AccountService) CreateAccount(req *models.CreateAccountRequest) error {
 	}
 
 	balance := models.Decimal(req.InitialBalance)
-	if err := s.accountRepo.Create(req.AccountID, balance); err != nil {
+	if err := s.accountRepo.Create(ctx, req.AccountID, balance); err != nil {
 		return fmt.Errorf("failed to create account: %w", err)
 	}
 
 	return nil
 }
 
-func (s *AccountService) GetAccount(accountID int64) (*models.Account, error) {
+func (s *AccountService) GetAccount(ctx context.Context, accountID int64) (*models.Account, error) {
 	if accountID <= 0 {
 		return nil, fmt.Errorf("account_id must be a positive integer")
 	}
 
-	account, err := s.accountRepo.GetByID(accountID)
+	account, err := s.accountRepo.GetByID(ctx, accountID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get account: %w", err)
 	}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/repository"
+)
+
+// fakeAPIKeyRepository keeps keys in memory and counts last-used writes.
+type fakeAPIKeyRepository struct {
+	keys    map[string]*models.APIKey
+	touches int
+}
+
+func newFakeAPIKeyRepository() *fakeAPIKeyRepository {
+	return &fakeAPIKeyRepository{keys: map[string]*models.APIKey{}}
+}
+
+func (r *fakeAPIKeyRepository) Create(ctx context.Context, name, prefix, keyHash string, scopes []string) (*models.APIKey, error) {
+	key := &models.APIKey{ID: int64(len(r.keys) + 1), Name: name, Prefix: prefix, Scopes: scopes, CreatedAt: time.Now().UTC()}
+	r.keys[keyHash] = key
+	copied := *key
+	return &copied, nil
+}
+
+func (r *fakeAPIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	key, ok := r.keys[keyHash]
+	if !ok {
+		return nil, repository.ErrAPIKeyNotFound
+	}
+	copied := *key
+	return &copied, nil
+}
+
+func (r *fakeAPIKeyRepository) List(ctx context.Context) ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+	for _, key := range r.keys {
+		keys = append(keys, *key)
+	}
+	return keys, nil
+}
+
+func (r *fakeAPIKeyRepository) Revoke(ctx context.Context, id int64) error {
+	for _, key := range r.keys {
+		if key.ID == id {
+			now := time.Now().UTC()
+			key.RevokedAt = &now
+			return nil
+		}
+	}
+	return repository.ErrAPIKeyNotFound
+}
+
+func (r *fakeAPIKeyRepository) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	r.touches++
+	for _, key := range r.keys {
+		if key.ID == id {
+			key.LastUsedAt = &at
+		}
+	}
+	return nil
+}
+
+const testAdminAPIKey = "admin-key-for-tests-0123456789abcdef"
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeAPIKeyRepository()
+	svc := NewAPIKeyService(repo, testAdminAPIKey)
+
+	created, err := svc.CreateKey(ctx, &models.CreateAPIKeyRequest{Name: "gateway", Scopes: []string{models.ScopeTransfersWrite}})
+	if err != nil {
+		t.Fatalf("CreateKey() error = %v", err)
+	}
+	if !strings.HasPrefix(created.Key, created.Prefix) || len(created.Key) <= len(created.Prefix) {
+		t.Errorf("key %q does not start with its prefix %q", created.Key, created.Prefix)
+	}
+	for hash := range repo.keys {
+		if strings.Contains(hash, created.Key) {
+			t.Error("the key is stored in plain text")
+		}
+	}
+
+	tests := []struct {
+		name     string
+		key      string
+		wantName string
+	}{
+		{name: "created key", key: created.Key, wantName: "gateway"},
+		{name: "bootstrap admin key", key: testAdminAPIKey, wantName: "admin"},
+		{name: "unknown key", key: "tak_unknown"},
+		{name: "empty key", key: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.Authenticate(ctx, tt.key)
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if tt.wantName == "" {
+				if got != nil {
+					t.Errorf("Authenticate() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || got.Name != tt.wantName {
+				t.Errorf("Authenticate() = %+v, want key %q", got, tt.wantName)
+			}
+		})
+	}
+
+	if err := svc.RevokeKey(ctx, created.ID); err != nil {
+		t.Fatalf("RevokeKey() error = %v", err)
+	}
+	if got, err := svc.Authenticate(ctx, created.Key); err != nil || got != nil {
+		t.Errorf("Authenticate() after revoke = %+v, %v, want nil", got, err)
+	}
+	if err := svc.RevokeKey(ctx, 99); err == nil || !strings.Contains(err.Error(), "API key not found") {
+		t.Errorf("RevokeKey(99) error = %v, want API key not found", err)
+	}
+}
+
+func TestAPIKeyService_AuthenticateTracksLastUse(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeAPIKeyRepository()
+	svc := NewAPIKeyService(repo, "")
+	now := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	created, err := svc.CreateKey(ctx, &models.CreateAPIKeyRequest{Name: "reporting", Scopes: []string{models.ScopeAccountsRead}})
+	if err != nil {
+		t.Fatalf("CreateKey() error = %v", err)
+	}
+
+	steps := []struct {
+		advance     time.Duration
+		wantTouches int
+	}{
+		{advance: 0, wantTouches: 1},
+		{advance: 30 * time.Second, wantTouches: 1},
+		{advance: 31 * time.Second, wantTouches: 2},
+	}
+	for i, step := range steps {
+		now = now.Add(step.advance)
+		key, err := svc.Authenticate(ctx, created.Key)
+		if err != nil || key == nil {
+			t.Fatalf("step %d: Authenticate() = %+v, %v", i, key, err)
+		}
+		if repo.touches != step.wantTouches {
+			t.Errorf("step %d: %d last-used writes, want %d", i, repo.touches, step.wantTouches)
+		}
+	}
+	if got := repo.keys[hashAPIKey(created.Key)].LastUsedAt; got == nil || !got.Equal(now) {
+		t.Errorf("LastUsedAt = %v, want %v", got, now)
+	}
+}
+
+func TestAPIKeyService_CreateKeyValidation(t *testing.T) {
+	svc := NewAPIKeyService(newFakeAPIKeyRepository(), "")
+	_, err := svc.CreateKey(context.Background(), &models.CreateAPIKeyRequest{Name: "x", Scopes: []string{"root"}})
+	if err == nil || !strings.Contains(err.Error(), "validation error") {
+		t.Errorf("CreateKey() error = %v, want validation error", err)
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/repository"
+)
+
+type BusinessDayService struct {
+	businessDayRepo *repository.BusinessDayRepository
+}
+
+func NewBusinessDayService(businessDayRepo *repository.BusinessDayRepository) *BusinessDayService {
+	return &BusinessDayService{
+		businessDayRepo: businessDayRepo,
+	}
+}
+
+func (s *BusinessDayService) CloseBusinessDay(ctx context.Context, req *models.CloseBusinessDayRequest) (*models.BusinessDay, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+
+	date, _ := models.ParseBusinessDate(req.BusinessDate)
+	if !date.AddDate(0, 0, 1).Before(time.Now()) {
+		return nil, fmt.Errorf("validation error: business_date %s cannot be closed before it has ended", req.BusinessDate)
+	}
+
+	day, err := s.businessDayRepo.Close(ctx, req.BusinessDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close business day: %w", err)
+	}
+
+	return day, nil
+}
+
+func (s *BusinessDayService) GetSnapshots(ctx context.Context, businessDate string) (*models.BusinessDaySnapshots, error) {
+	if _, err := models.ParseBusinessDate(businessDate); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+
+	day, err := s.businessDayRepo.GetByDate(ctx, businessDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get business day: %w", err)
+	}
+
+	snapshots, err := s.businessDayRepo.ListSnapshots(ctx, businessDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance snapshots: %w", err)
+	}
+
+	return &models.BusinessDaySnapshots{
+		BusinessDay: day,
+		Snapshots:   snapshots,
+	}, nil
+}
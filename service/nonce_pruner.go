@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"triplea-backend-assignment/logging"
+	"triplea-backend-assignment/repository"
+)
+
+// NoncePruner deletes the nonces of signed requests once their timestamps
+// are too old to be accepted, so the table only holds the replay window.
+type NoncePruner struct {
+	nonceRepo *repository.NonceRepository
+	interval  time.Duration
+}
+
+func NewNoncePruner(nonceRepo *repository.NonceRepository, interval time.Duration) *NoncePruner {
+	return &NoncePruner{
+		nonceRepo: nonceRepo,
+		interval:  interval,
+	}
+}
+
+// Run prunes every interval until ctx is done.
+func (p *NoncePruner) Run(ctx context.Context) {
+	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("component", "nonce_pruner"))
+	logger := logging.FromContext(ctx)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := p.nonceRepo.DeleteExpired(ctx, time.Now())
+		if err != nil {
+			logger.ErrorContext(ctx, "nonce pruning failed", "error", err.Error())
+			continue
+		}
+		if deleted > 0 {
+			logger.DebugContext(ctx, "pruned expired nonces", "deleted", deleted)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"triplea-backend-assignment/logging"
+	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/repository"
+)
+
+// PartitionMaintainer keeps monthly partitions of the transactions table
+// ready ahead of time, so new transactions never have to fall back to the
+// default partition.
+type PartitionMaintainer struct {
+	partitionRepo *repository.PartitionRepository
+	interval      time.Duration
+	monthsAhead   int
+}
+
+func NewPartitionMaintainer(partitionRepo *repository.PartitionRepository, interval time.Duration, monthsAhead int) *PartitionMaintainer {
+	return &PartitionMaintainer{
+		partitionRepo: partitionRepo,
+		interval:      interval,
+		monthsAhead:   monthsAhead,
+	}
+}
+
+// Run ensures the partitions exist straight away and then every interval
+// until ctx is done.
+func (m *PartitionMaintainer) Run(ctx context.Context) {
+	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("component", "partition_maintainer"))
+	logger := logging.FromContext(ctx)
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		created, err := m.EnsureOnce(ctx, time.Now())
+		if err != nil {
+			logger.ErrorContext(ctx, "partition maintenance failed", "error", err.Error())
+		}
+		for _, name := range created {
+			logger.InfoContext(ctx, "created partition", "partition", name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EnsureOnce creates any missing partition from the month of now through
+// monthsAhead months later and returns the names it created.
+func (m *PartitionMaintainer) EnsureOnce(ctx context.Context, now time.Time) ([]string, error) {
+	existing, err := m.partitionRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var created []string
+	for _, partition := range missingPartitions(existing, now, m.monthsAhead) {
+		if err := m.partitionRepo.Create(ctx, partition); err != nil {
+			return created, err
+		}
+		created = append(created, partition.Name)
+	}
+	return created, nil
+}
+
+func missingPartitions(existing []models.TransactionPartition, now time.Time, monthsAhead int) []models.TransactionPartition {
+	have := map[string]bool{}
+	for _, partition := range existing {
+		have[partition.Name] = true
+	}
+
+	var missing []models.TransactionPartition
+	month := models.MonthPartition(now)
+	for i := 0; i <= monthsAhead; i++ {
+		if !have[month.Name] {
+			missing = append(missing, month)
+		}
+		month = models.MonthPartition(month.End)
+	}
+	return missing
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"triplea-backend-assignment/models"
+)
+
+func TestMissingPartitions(t *testing.T) {
+	// Late on 31 January in New York is already February in UTC.
+	now := time.Date(2024, time.January, 31, 22, 0, 0, 0, time.FixedZone("EST", -5*60*60))
+	month := func(m time.Month, year int) models.TransactionPartition {
+		return models.MonthPartition(time.Date(year, m, 1, 0, 0, 0, 0, time.UTC))
+	}
+	names := func(partitions []models.TransactionPartition) []string {
+		var result []string
+		for _, partition := range partitions {
+			result = append(result, partition.Name)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		existing []models.TransactionPartition
+		want     []string
+	}{
+		{
+			name: "none exist",
+			want: []string{"transactions_p202402", "transactions_p202403", "transactions_p202404"},
+		},
+		{
+			name:     "some exist",
+			existing: []models.TransactionPartition{month(time.January, 2024), month(time.February, 2024), month(time.April, 2024)},
+			want:     []string{"transactions_p202403"},
+		},
+		{
+			name:     "all exist",
+			existing: []models.TransactionPartition{month(time.February, 2024), month(time.March, 2024), month(time.April, 2024)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := names(missingPartitions(tt.existing, now, 2))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("missingPartitions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
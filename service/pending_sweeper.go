@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"triplea-backend-assignment/logging"
+	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/repository"
+)
+
+type SweepResult struct {
+	Completed  int
+	Failed     int
+	Unresolved int
+}
+
+// PendingSweeper resolves transactions left in pending status, e.g. by a
+// crashed process or an asynchronous flow that never finished. The ledger
+// decides the outcome: if both balances already include the transfer it is
+// completed, if neither does it is failed, otherwise it is left for a human.
+type PendingSweeper struct {
+	txManager       repository.TxManager
+	transactionRepo repository.TransactionRepository
+	accountRepo     repository.AccountRepository
+	interval        time.Duration
+	maxAge          time.Duration
+	batchSize       int
+}
+
+func NewPendingSweeper(
+	txManager repository.TxManager,
+	transactionRepo repository.TransactionRepository,
+	accountRepo repository.AccountRepository,
+	interval time.Duration,
+	maxAge time.Duration,
+	batchSize int,
+) *PendingSweeper {
+	return &PendingSweeper{
+		txManager:       txManager,
+		transactionRepo: transactionRepo,
+		accountRepo:     accountRepo,
+		interval:        interval,
+		maxAge:          maxAge,
+		batchSize:       batchSize,
+	}
+}
+
+// Run sweeps every interval until ctx is done. A sweep in progress is
+// abandoned and its open transaction rolled back.
+func (s *PendingSweeper) Run(ctx context.Context) {
+	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("component", "pending_sweeper"))
+	logger := logging.FromContext(ctx)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := s.SweepOnce(ctx)
+			if err != nil {
+				logger.ErrorContext(ctx, "sweep failed", "error", err.Error())
+				continue
+			}
+			if result.Completed+result.Failed+result.Unresolved > 0 {
+				logger.InfoContext(ctx, "swept pending transactions",
+					"completed", result.Completed, "failed", result.Failed, "unresolved", result.Unresolved)
+			}
+		}
+	}
+}
+
+func (s *PendingSweeper) SweepOnce(ctx context.Context) (SweepResult, error) {
+	var result SweepResult
+
+	transactions, err := s.transactionRepo.ListPendingOlderThan(ctx, s.maxAge, s.batchSize)
+	if err != nil {
+		return result, fmt.Errorf("failed to list pending transactions: %w", err)
+	}
+
+	for i := range transactions {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		status, err := s.resolve(ctx, transactions[i].ID)
+		if err != nil {
+			logging.FromContext(ctx).WarnContext(ctx, "failed to resolve pending transaction",
+				"transaction_id", transactions[i].ID, "error", err.Error())
+			result.Unresolved++
+			continue
+		}
+		switch status {
+		case models.TransactionStatusCompleted:
+			result.Completed++
+		case models.TransactionStatusFailed:
+			result.Failed++
+		default:
+			result.Unresolved++
+		}
+	}
+
+	return result, nil
+}
+
+func (s *PendingSweeper) resolve(ctx context.Context, transactionID int64) (string, error) {
+	tx, err := s.txManager.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	transaction, err := s.transactionRepo.GetByIDWithLock(ctx, tx, transactionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction: %w", err)
+	}
+	if transaction.Status != models.TransactionStatusPending {
+		return transaction.Status, nil
+	}
+
+	// Lock in ascending ID order so the sweeper never deadlocks with itself.
+	first, second := transaction.SourceAccountID, transaction.DestinationAccountID
+	if second < first {
+		first, second = second, first
+	}
+	if _, err := s.accountRepo.GetByIDWithLock(ctx, tx, first); err != nil {
+		return "", fmt.Errorf("failed to lock account %d: %w", first, err)
+	}
+	if _, err := s.accountRepo.GetByIDWithLock(ctx, tx, second); err != nil {
+		return "", fmt.Errorf("failed to lock account %d: %w", second, err)
+	}
+
+	applied, notApplied, err := s.transactionRepo.LedgerEvidence(ctx, tx, transaction)
+	if err != nil {
+		return "", err
+	}
+
+	var status string
+	switch {
+	case applied:
+		status = models.TransactionStatusCompleted
+		err = s.transactionRepo.UpdateStatus(ctx, tx, transaction.ID, status)
+	case notApplied:
+		status = models.TransactionStatusFailed
+		err = s.transactionRepo.MarkFailed(ctx, tx, transaction.ID, models.FailureReasonNotApplied)
+	default:
+		return models.TransactionStatusPending, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return status, nil
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/repository"
+)
+
+func createPendingTransaction(t *testing.T, store *repository.MemoryStore, source, destination int64, amount string) *models.Transaction {
+	t.Helper()
+	ctx := context.Background()
+	tx, err := store.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	defer tx.Rollback()
+
+	transaction, err := store.Transactions().Create(ctx, tx, source, destination, models.Decimal(amount))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	return transaction
+}
+
+func TestPendingSweeper_SweepOnce(t *testing.T) {
+	ctx := context.Background()
+
+	store := repository.NewMemoryStore()
+	for accountID, balance := range map[int64]string{1: "100", 2: "0", 3: "100", 4: "0"} {
+		if err := store.Accounts().Create(ctx, accountID, models.Decimal(balance)); err != nil {
+			t.Fatalf("failed to create account %d: %v", accountID, err)
+		}
+	}
+
+	// 1 -> 2 moved the money but was never marked completed; 3 -> 4 never
+	// touched the balances.
+	applied := createPendingTransaction(t, store, 1, 2, "40")
+	if err := store.Accounts().UpdateBalance(ctx, 1, "60"); err != nil {
+		t.Fatalf("UpdateBalance() error = %v", err)
+	}
+	if err := store.Accounts().UpdateBalance(ctx, 2, "40"); err != nil {
+		t.Fatalf("UpdateBalance() error = %v", err)
+	}
+	notApplied := createPendingTransaction(t, store, 3, 4, "25")
+	time.Sleep(10 * time.Millisecond)
+
+	sweeper := NewPendingSweeper(store, store.Transactions(), store.Accounts(), time.Minute, time.Millisecond, 10)
+	result, err := sweeper.SweepOnce(ctx)
+	if err != nil {
+		t.Fatalf("SweepOnce() error = %v", err)
+	}
+	if result != (SweepResult{Completed: 1, Failed: 1}) {
+		t.Errorf("SweepOnce() = %+v, want 1 completed and 1 failed", result)
+	}
+
+	transaction, err := store.Transactions().GetByID(ctx, applied.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if transaction.Status != models.TransactionStatusCompleted {
+		t.Errorf("applied transaction status = %q, want completed", transaction.Status)
+	}
+
+	transaction, err = store.Transactions().GetByID(ctx, notApplied.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if transaction.Status != models.TransactionStatusFailed || transaction.FailureReason != models.FailureReasonNotApplied {
+		t.Errorf("unapplied transaction = %q/%q, want failed/not_applied", transaction.Status, transaction.FailureReason)
+	}
+
+	result, err = sweeper.SweepOnce(ctx)
+	if err != nil {
+		t.Fatalf("second SweepOnce() error = %v", err)
+	}
+	if result != (SweepResult{}) {
+		t.Errorf("second SweepOnce() = %+v, want nothing left to sweep", result)
+	}
+}
+
+func TestPendingSweeper_LeavesTransactionsThatAreNotStale(t *testing.T) {
+	ctx := context.Background()
+
+	store := repository.NewMemoryStore()
+	for _, accountID := range []int64{1, 2} {
+		if err := store.Accounts().Create(ctx, accountID, "100"); err != nil {
+			t.Fatalf("failed to create account %d: %v", accountID, err)
+		}
+	}
+	pending := createPendingTransaction(t, store, 1, 2, "40")
+
+	sweeper := NewPendingSweeper(store, store.Transactions(), store.Accounts(), time.Minute, time.Hour, 10)
+	result, err := sweeper.SweepOnce(ctx)
+	if err != nil {
+		t.Fatalf("SweepOnce() error = %v", err)
+	}
+	if result != (SweepResult{}) {
+		t.Errorf("SweepOnce() = %+v, want nothing swept", result)
+	}
+
+	transaction, err := store.Transactions().GetByID(ctx, pending.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if transaction.Status != models.TransactionStatusPending {
+		t.Errorf("transaction status = %q, want pending", transaction.Status)
+	}
+}
+
+func TestPendingSweeper_LeavesPartiallyAppliedTransactions(t *testing.T) {
+	ctx := context.Background()
+
+	store := repository.NewMemoryStore()
+	for accountID, balance := range map[int64]string{1: "100", 2: "0"} {
+		if err := store.Accounts().Create(ctx, accountID, models.Decimal(balance)); err != nil {
+			t.Fatalf("failed to create account %d: %v", accountID, err)
+		}
+	}
+	// Only the debit reached the ledger, so neither outcome is safe.
+	pending := createPendingTransaction(t, store, 1, 2, "40")
+	if err := store.Accounts().UpdateBalance(ctx, 1, "60"); err != nil {
+		t.Fatalf("UpdateBalance() error = %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	sweeper := NewPendingSweeper(store, store.Transactions(), store.Accounts(), time.Minute, time.Millisecond, 10)
+	result, err := sweeper.SweepOnce(ctx)
+	if err != nil {
+		t.Fatalf("SweepOnce() error = %v", err)
+	}
+	if result != (SweepResult{Unresolved: 1}) {
+		t.Errorf("SweepOnce() = %+v, want 1 unresolved", result)
+	}
+
+	transaction, err := store.Transactions().GetByID(ctx, pending.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if transaction.Status != models.TransactionStatusPending {
+		t.Errorf("transaction status = %q, want pending", transaction.Status)
+	}
+}
+
+func TestPendingSweeper_LocksAccountsInIDOrder(t *testing.T) {
+	ctx := context.Background()
+
+	store := repository.NewMemoryStore()
+	for _, accountID := range []int64{1, 2} {
+		if err := store.Accounts().Create(ctx, accountID, "100"); err != nil {
+			t.Fatalf("failed to create account %d: %v", accountID, err)
+		}
+	}
+	createPendingTransaction(t, store, 1, 2, "40")
+	createPendingTransaction(t, store, 2, 1, "25")
+	time.Sleep(10 * time.Millisecond)
+
+	accounts := &lockRecorder{AccountRepository: store.Accounts()}
+	sweeper := NewPendingSweeper(store, store.Transactions(), accounts, time.Minute, time.Millisecond, 10)
+	result, err := sweeper.SweepOnce(ctx)
+	if err != nil {
+		t.Fatalf("SweepOnce() error = %v", err)
+	}
+	if result != (SweepResult{Failed: 2}) {
+		t.Errorf("SweepOnce() = %+v, want 2 failed", result)
+	}
+	want := []int64{1, 2, 1, 2}
+	if len(accounts.locked) != len(want) {
+		t.Fatalf("locked accounts %v, want %v", accounts.locked, want)
+	}
+	for i := range want {
+		if accounts.locked[i] != want[i] {
+			t.Fatalf("locked accounts %v, want %v", accounts.locked, want)
+		}
+	}
+}
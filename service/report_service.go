@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/repository"
+)
+
+type ReportService struct {
+	reportRepo *repository.ReportRepository
+}
+
+func NewReportService(reportRepo *repository.ReportRepository) *ReportService {
+	return &ReportService{
+		reportRepo: reportRepo,
+	}
+}
+
+func (s *ReportService) TrialBalance(ctx context.Context, req *models.TrialBalanceRequest) (*models.TrialBalanceReport, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+
+	report, err := s.reportRepo.TrialBalance(ctx, req.From, req.To, req.Threshold())
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trial balance: %w", err)
+	}
+
+	return report, nil
+}
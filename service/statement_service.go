@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/repository"
+)
+
+type StatementWriter interface {
+	WriteOpening(req *models.StatementRequest, opening models.Decimal) error
+	WriteLine(line *models.StatementLine) error
+	WriteClosing(closing models.Decimal) error
+}
+
+type StatementService struct {
+	statementRepo *repository.StatementRepository
+}
+
+func NewStatementService(statementRepo *repository.StatementRepository) *StatementService {
+	return &StatementService{
+		statementRepo: statementRepo,
+	}
+}
+
+func (s *StatementService) WriteStatement(ctx context.Context, req *models.StatementRequest, w StatementWriter) error {
+	if err := req.Validate(); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+
+	closing, err := s.statementRepo.Stream(
+		ctx,
+		req.AccountID,
+		req.From,
+		req.To,
+		func(opening models.Decimal) error {
+			return w.WriteOpening(req, opening)
+		},
+		w.WriteLine,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to generate statement: %w", err)
+	}
+
+	if err := w.WriteClosing(closing); err != nil {
+		return fmt.Errorf("failed to generate statement: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/repository"
+)
+
+// TransactionArchiver moves old partitions of the transactions table into
+// gzip-compressed NDJSON files, one transaction per line, and drops them from
+// the database.
+type TransactionArchiver struct {
+	partitionRepo *repository.PartitionRepository
+	dir           string
+}
+
+func NewTransactionArchiver(partitionRepo *repository.PartitionRepository, dir string) *TransactionArchiver {
+	return &TransactionArchiver{
+		partitionRepo: partitionRepo,
+		dir:           dir,
+	}
+}
+
+// ArchiveBefore archives every partition that ends on or before cutoff,
+// oldest first. It stops at the first failure; partitions archived until then
+// stay archived.
+func (a *TransactionArchiver) ArchiveBefore(ctx context.Context, cutoff, now time.Time) ([]models.ArchivedPartition, error) {
+	if err := validateArchiveCutoff(cutoff, now); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(a.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	partitions, err := a.partitionRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	archived := []models.ArchivedPartition{}
+	for _, partition := range partitions {
+		if partition.End.After(cutoff) {
+			continue
+		}
+		result, err := a.archive(ctx, partition)
+		if err != nil {
+			return archived, err
+		}
+		archived = append(archived, result)
+	}
+	return archived, nil
+}
+
+func (a *TransactionArchiver) archive(ctx context.Context, partition models.TransactionPartition) (models.ArchivedPartition, error) {
+	path := filepath.Join(a.dir, partition.Name+".ndjson.gz")
+	file, err := createArchiveFile(path)
+	if err != nil {
+		return models.ArchivedPartition{}, err
+	}
+	defer file.abort()
+
+	rows, err := a.partitionRepo.Archive(ctx, partition, filepath.Base(path), file.write, file.commit)
+	if err != nil {
+		return models.ArchivedPartition{}, err
+	}
+	return models.ArchivedPartition{Name: partition.Name, Rows: rows, File: path}, nil
+}
+
+// validateArchiveCutoff refuses cutoffs after the start of the current UTC
+// month, so the partition taking new transactions is never archived.
+func validateArchiveCutoff(cutoff, now time.Time) error {
+	limit := models.MonthPartition(now).Start
+	if cutoff.After(limit) {
+		return fmt.Errorf("validation error: before cannot be later than %s", limit.Format(models.BusinessDateLayout))
+	}
+	return nil
+}
+
+// archiveFile writes to a temporary file that only replaces path once
+// commit has synced it, so a failed export never leaves a partial archive.
+type archiveFile struct {
+	path      string
+	file      *os.File
+	gzip      *gzip.Writer
+	encoder   *json.Encoder
+	committed bool
+}
+
+func createArchiveFile(path string) (*archiveFile, error) {
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive file: %w", err)
+	}
+	gz := gzip.NewWriter(file)
+	return &archiveFile{path: path, file: file, gzip: gz, encoder: json.NewEncoder(gz)}, nil
+}
+
+func (f *archiveFile) write(transaction *models.Transaction) error {
+	return f.encoder.Encode(transaction)
+}
+
+func (f *archiveFile) commit() error {
+	if err := f.gzip.Close(); err != nil {
+		return err
+	}
+	if err := f.file.Sync(); err != nil {
+		return err
+	}
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.file.Name(), f.path); err != nil {
+		return err
+	}
+	f.committed = true
+	return nil
+}
+
+// abort removes the temporary file unless commit succeeded.
+func (f *archiveFile) abort() {
+	if f.committed {
+		return
+	}
+	f.file.Close()
+	os.Remove(f.file.Name())
+}
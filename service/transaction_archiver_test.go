@@ -0,0 +1,116 @@
+package service
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"triplea-backend-assignment/models"
+)
+
+func TestValidateArchiveCutoff(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		cutoff  time.Time
+		wantErr bool
+	}{
+		{name: "earlier month", cutoff: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "start of current month", cutoff: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "inside current month", cutoff: time.Date(2024, time.March, 2, 0, 0, 0, 0, time.UTC), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateArchiveCutoff(tt.cutoff, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateArchiveCutoff() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestArchiveFile(t *testing.T) {
+	dir := t.TempDir()
+	transactions := []models.Transaction{
+		{ID: 1, SourceAccountID: 1, DestinationAccountID: 2, Amount: "10", Status: models.TransactionStatusCompleted},
+		{ID: 2, SourceAccountID: 2, DestinationAccountID: 1, Amount: "5", Status: models.TransactionStatusFailed},
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		path := filepath.Join(dir, "committed.ndjson.gz")
+		file, err := createArchiveFile(path)
+		if err != nil {
+			t.Fatalf("createArchiveFile() error = %v", err)
+		}
+		defer file.abort()
+		for i := range transactions {
+			if err := file.write(&transactions[i]); err != nil {
+				t.Fatalf("write() error = %v", err)
+			}
+		}
+		if err := file.commit(); err != nil {
+			t.Fatalf("commit() error = %v", err)
+		}
+
+		got := readArchive(t, path)
+		if len(got) != len(transactions) {
+			t.Fatalf("archive has %d lines, want %d", len(got), len(transactions))
+		}
+		for i := range got {
+			if got[i].ID != transactions[i].ID || got[i].Amount != transactions[i].Amount {
+				t.Errorf("line %d = %+v, want %+v", i, got[i], transactions[i])
+			}
+		}
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		path := filepath.Join(dir, "aborted.ndjson.gz")
+		file, err := createArchiveFile(path)
+		if err != nil {
+			t.Fatalf("createArchiveFile() error = %v", err)
+		}
+		if err := file.write(&transactions[0]); err != nil {
+			t.Fatalf("write() error = %v", err)
+		}
+		file.abort()
+
+		for _, name := range []string{path, path + ".tmp"} {
+			if _, err := os.Stat(name); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("%s exists after abort", filepath.Base(name))
+			}
+		}
+	})
+}
+
+func readArchive(t *testing.T, path string) []models.Transaction {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+
+	var transactions []models.Transaction
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		var transaction models.Transaction
+		if err := json.Unmarshal(scanner.Bytes(), &transaction); err != nil {
+			t.Fatalf("invalid archive line %q: %v", scanner.Text(), err)
+		}
+		transactions = append(transactions, transaction)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	return transactions
+}
@@ -1,104 +1,323 @@
 package service
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
-	"triplea-backend-assignment/database"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"triplea-backend-assignment/logging"
 	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/ratelimit"
 	"triplea-backend-assignment/repository"
+	"triplea-backend-assignment/tracing"
+)
+
+// Results of a transfer attempt, as recorded by a TransferRecorder.
+const (
+	TransferResultCompleted         = "completed"
+	TransferResultInsufficientFunds = "insufficient_funds"
+	TransferResultInvalid           = "invalid"
+	TransferResultAccountNotFound   = "account_not_found"
+	TransferResultRateLimited       = "rate_limited"
+	TransferResultTimeout           = "timeout"
+	TransferResultCanceled          = "canceled"
+	TransferResultError             = "error"
 )
 
+// TransferRecorder records the result and amount of every transfer attempt.
+// *metrics.Metrics implements it.
+type TransferRecorder interface {
+	RecordTransfer(result string, amount float64)
+}
+
 type TransactionService struct {
-	transactionRepo *repository.TransactionRepository
-	accountRepo     *repository.AccountRepository
+	txManager       repository.TxManager
+	transactionRepo repository.TransactionRepository
+	accountRepo     repository.AccountRepository
+	recorder        TransferRecorder
+	debits          *ratelimit.Limiter
 }
 
 func NewTransactionService(
-	transactionRepo *repository.TransactionRepository,
-	accountRepo *repository.AccountRepository,
+	txManager repository.TxManager,
+	transactionRepo repository.TransactionRepository,
+	accountRepo repository.AccountRepository,
+	recorder TransferRecorder,
+	debits *ratelimit.Limiter,
 ) *TransactionService {
 	return &TransactionService{
+		txManager:       txManager,
 		transactionRepo: transactionRepo,
 		accountRepo:     accountRepo,
+		recorder:        recorder,
+		debits:          debits,
 	}
 }
 
-func (s *TransactionService) ProcessTransaction(req *models.CreateTransactionRequest) error {
-	if err := s.validateBeforeTxn(req); err != nil {
-		return err
+// ProcessTransaction moves money between two accounts. It is traced as a
+// span with one child span per phase: validate, lock, update and commit.
+func (s *TransactionService) ProcessTransaction(ctx context.Context, req *models.CreateTransactionRequest) error {
+	ctx, span := tracing.Tracer().Start(ctx, "TransactionService.ProcessTransaction",
+		trace.WithAttributes(
+			attribute.Int64("transfer.source_account_id", req.SourceAccountID),
+			attribute.Int64("transfer.destination_account_id", req.DestinationAccountID),
+		))
+	err := s.processTransaction(ctx, req)
+	result := transferResult(ctx, err)
+	span.SetAttributes(attribute.String("transfer.result", result))
+	tracing.End(span, err)
+
+	// An amount that does not parse is recorded as zero under "invalid".
+	amount, _ := models.Decimal(req.Amount).Float64()
+	s.recorder.RecordTransfer(result, amount)
+	return err
+}
+
+// inPhase runs one phase of a transfer in its own span.
+func inPhase(ctx context.Context, name string, run func(ctx context.Context) error) error {
+	ctx, span := tracing.Tracer().Start(ctx, "transfer."+name)
+	err := run(ctx)
+	tracing.End(span, err)
+	return err
+}
+
+// transferResult classifies the error of a transfer attempt the way the
+// handlers do.
+func transferResult(ctx context.Context, err error) string {
+	switch {
+	case err == nil:
+		return TransferResultCompleted
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded),
+		strings.Contains(err.Error(), "canceling statement due to statement timeout"),
+		strings.Contains(err.Error(), "canceling statement due to lock timeout"):
+		return TransferResultTimeout
+	case errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled):
+		return TransferResultCanceled
+	case errors.As(err, new(*ratelimit.ExceededError)):
+		return TransferResultRateLimited
+	case errors.Is(err, repository.ErrInsufficientFunds) || strings.Contains(err.Error(), "insufficient balance"):
+		return TransferResultInsufficientFunds
+	case strings.Contains(err.Error(), "not found"):
+		return TransferResultAccountNotFound
+	case strings.Contains(err.Error(), "validation error") || strings.Contains(err.Error(), "invalid amount"):
+		return TransferResultInvalid
+	default:
+		return TransferResultError
 	}
+}
 
-	amount, err := models.Decimal(req.Amount).Float64()
+func (s *TransactionService) processTransaction(ctx context.Context, req *models.CreateTransactionRequest) error {
+	var amount float64
+	err := inPhase(ctx, "validate", func(ctx context.Context) error {
+		if err := s.validateBeforeTxn(ctx, req); err != nil {
+			return err
+		}
+		var err error
+		amount, err = models.Decimal(req.Amount).Float64()
+		if err != nil {
+			return fmt.Errorf("invalid amount format: %w", err)
+		}
+		return s.allowDebit(req.SourceAccountID)
+	})
 	if err != nil {
-		return fmt.Errorf("invalid amount format: %w", err)
+		return err
 	}
 
-	tx, err := database.DB.Begin()
+	var tx repository.Tx
+	defer func() {
+		if tx != nil {
+			tx.Rollback()
+		}
+	}()
+	var sourceAccount, destAccount *models.Account
+	err = inPhase(ctx, "lock", func(ctx context.Context) error {
+		var err error
+		tx, err = s.txManager.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+
+		// Lock in ascending ID order, like the pending sweeper, so that
+		// transfers in opposite directions cannot deadlock.
+		sourceFirst := req.SourceAccountID < req.DestinationAccountID
+		first, second := req.SourceAccountID, req.DestinationAccountID
+		if !sourceFirst {
+			first, second = second, first
+		}
+		firstAccount, err := s.accountRepo.GetByIDWithLock(ctx, tx, first)
+		if err != nil {
+			return fmt.Errorf("failed to get %s account: %w", accountRole(sourceFirst), err)
+		}
+		secondAccount, err := s.accountRepo.GetByIDWithLock(ctx, tx, second)
+		if err != nil {
+			return fmt.Errorf("failed to get %s account: %w", accountRole(!sourceFirst), err)
+		}
+
+		sourceAccount, destAccount = firstAccount, secondAccount
+		if !sourceFirst {
+			sourceAccount, destAccount = secondAccount, firstAccount
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return err
 	}
-	defer tx.Rollback()
 
-	sourceAccount, err := s.accountRepo.GetByIDWithLock(tx, req.SourceAccountID)
+	var transaction *models.Transaction
+	declined := false
+	err = inPhase(ctx, "update", func(ctx context.Context) error {
+		sourceBalance, err := sourceAccount.Balance.Float64()
+		if err != nil {
+			return fmt.Errorf("invalid source balance format: %w", err)
+		}
+
+		overdraftLimit, err := sourceAccount.OverdraftLimit.Float64()
+		if err != nil {
+			return fmt.Errorf("invalid source overdraft limit format: %w", err)
+		}
+
+		destBalance, err := destAccount.Balance.Float64()
+		if err != nil {
+			return fmt.Errorf("invalid destination balance format: %w", err)
+		}
+
+		if sourceBalance+overdraftLimit < amount {
+			// The attempt is kept as a failed transaction instead of vanishing
+			// with the rollback.
+			declined = true
+			transaction, err = s.transactionRepo.CreateFailed(ctx, tx, req.SourceAccountID, req.DestinationAccountID,
+				models.Decimal(req.Amount), models.FailureReasonInsufficientFunds, sourceAccount.Balance)
+			if err != nil {
+				return fmt.Errorf("failed to record failed transaction: %w", err)
+			}
+			return nil
+		}
+
+		newSourceBalance := sourceBalance - amount
+		newDestBalance := destBalance + amount
+
+		transaction, err = s.transactionRepo.Create(ctx, tx, req.SourceAccountID, req.DestinationAccountID, models.Decimal(req.Amount))
+		if err != nil {
+			return fmt.Errorf("failed to create transaction record: %w", err)
+		}
+
+		sourceBalanceStr := fmt.Sprintf("%.10f", newSourceBalance)
+		if err := s.accountRepo.UpdateBalanceInTx(ctx, tx, req.SourceAccountID, models.Decimal(sourceBalanceStr)); err != nil {
+			return fmt.Errorf("failed to update source account balance: %w", err)
+		}
+
+		destBalanceStr := fmt.Sprintf("%.10f", newDestBalance)
+		if err := s.accountRepo.UpdateBalanceInTx(ctx, tx, req.DestinationAccountID, models.Decimal(destBalanceStr)); err != nil {
+			return fmt.Errorf("failed to update destination account balance: %w", err)
+		}
+
+		if err := s.transactionRepo.UpdateStatus(ctx, tx, transaction.ID, models.TransactionStatusCompleted); err != nil {
+			return fmt.Errorf("failed to update transaction status: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get source account: %w", err)
+		return err
 	}
 
-	destAccount, err := s.accountRepo.GetByIDWithLock(tx, req.DestinationAccountID)
+	err = inPhase(ctx, "commit", func(ctx context.Context) error {
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get destination account: %w", err)
+		return err
 	}
 
-	sourceBalance, err := sourceAccount.Balance.Float64()
-	if err != nil {
-		return fmt.Errorf("invalid source balance format: %w", err)
+	if declined {
+		logging.FromContext(ctx).InfoContext(ctx, "transfer declined",
+			"transaction_id", transaction.ID, "source_account_id", req.SourceAccountID,
+			"destination_account_id", req.DestinationAccountID, "amount", req.Amount,
+			"reason", models.FailureReasonInsufficientFunds)
+		return fmt.Errorf("insufficient balance in source account %d", req.SourceAccountID)
 	}
+	logging.FromContext(ctx).InfoContext(ctx, "transfer completed",
+		"transaction_id", transaction.ID, "source_account_id", req.SourceAccountID,
+		"destination_account_id", req.DestinationAccountID, "amount", req.Amount)
 
-	destBalance, err := destAccount.Balance.Float64()
-	if err != nil {
-		return fmt.Errorf("invalid destination balance format: %w", err)
+	return nil
+}
+
+func (s *TransactionService) GetTransaction(ctx context.Context, transactionID int64) (*models.Transaction, error) {
+	if transactionID <= 0 {
+		return nil, fmt.Errorf("transaction_id must be a positive integer")
 	}
 
-	if sourceBalance < amount {
-		return fmt.Errorf("insufficient balance in source account %d", req.SourceAccountID)
+	transaction, err := s.transactionRepo.GetByID(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
 	}
 
-	newSourceBalance := sourceBalance - amount
-	newDestBalance := destBalance + amount
+	return transaction, nil
+}
+
+func (s *TransactionService) ListAccountTransactions(ctx context.Context, req *models.TransactionHistoryRequest) (*models.TransactionHistory, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
 
-	transaction, err := s.transactionRepo.Create(tx, req.SourceAccountID, req.DestinationAccountID, models.Decimal(req.Amount))
+	exists, err := s.accountRepo.Exists(ctx, req.AccountID)
 	if err != nil {
-		return fmt.Errorf("failed to create transaction record: %w", err)
+		return nil, fmt.Errorf("failed to check account existence: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("account not found")
 	}
 
-	sourceBalanceStr := fmt.Sprintf("%.10f", newSourceBalance)
-	if err := s.updateAccountBalanceInTx(tx, req.SourceAccountID, models.Decimal(sourceBalanceStr)); err != nil {
-		return fmt.Errorf("failed to update source account balance: %w", err)
+	limit := req.Limit
+	if limit == 0 {
+		limit = models.DefaultTransactionHistoryLimit
 	}
 
-	destBalanceStr := fmt.Sprintf("%.10f", newDestBalance)
-	if err := s.updateAccountBalanceInTx(tx, req.DestinationAccountID, models.Decimal(destBalanceStr)); err != nil {
-		return fmt.Errorf("failed to update destination account balance: %w", err)
+	transactions, err := s.transactionRepo.ListByAccount(ctx, req.AccountID, req.Status, req.BeforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
 	}
 
-	if err := s.transactionRepo.UpdateStatus(tx, transaction.ID, models.TransactionStatusCompleted); err != nil {
-		return fmt.Errorf("failed to update transaction status: %w", err)
+	history := &models.TransactionHistory{Transactions: transactions}
+	if len(transactions) == limit {
+		history.NextBeforeID = transactions[len(transactions)-1].ID
 	}
+	return history, nil
+}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+func accountRole(source bool) string {
+	if source {
+		return "source"
 	}
+	return "destination"
+}
 
+// allowDebit applies the per-account debit limit, if there is one, which
+// holds however many clients the debits come from.
+func (s *TransactionService) allowDebit(accountID int64) error {
+	if s.debits == nil {
+		return nil
+	}
+	decision := s.debits.Allow(strconv.FormatInt(accountID, 10))
+	if !decision.Allowed {
+		return fmt.Errorf("too many debits from account %d: %w", accountID, &ratelimit.ExceededError{Decision: decision})
+	}
 	return nil
 }
 
-func (s *TransactionService) validateBeforeTxn(req *models.CreateTransactionRequest) error {
+func (s *TransactionService) validateBeforeTxn(ctx context.Context, req *models.CreateTransactionRequest) error {
 	if err := req.Validate(); err != nil {
 		return fmt.Errorf("validation error: %w", err)
 	}
 
-	sourceExists, err := s.accountRepo.Exists(req.SourceAccountID)
+	sourceExists, err := s.accountRepo.Exists(ctx, req.SourceAccountID)
 	if err != nil {
 		return fmt.Errorf("failed to check source account existence: %w", err)
 	}
@@ -106,7 +325,7 @@ func (s *TransactionService) validateBeforeTxn(req *models.CreateTransactionRequ
 		return fmt.Errorf("source account %d not found", req.SourceAccountID)
 	}
 
-	destExists, err := s.accountRepo.Exists(req.DestinationAccountID)
+	destExists, err := s.accountRepo.Exists(ctx, req.DestinationAccountID)
 	if err != nil {
 		return fmt.Errorf("failed to check destination account existence: %w", err)
 	}
@@ -116,20 +335,3 @@ func (s *TransactionService) validateBeforeTxn(req *models.CreateTransactionRequ
 
 	return nil
 }
-
-func (s *TransactionService) updateAccountBalanceInTx(tx *sql.Tx, accountID int64, newBalance models.Decimal) error {
-	query := `UPDATE accounts SET balance = $1, updated_at = CURRENT_TIMESTAMP WHERE account_id = $2`
-	result, err := tx.Exec(query, newBalance, accountID)
-	if err != nil {
-		return fmt.Errorf("failed to update account balance: %w", err)
-	}
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("account not found")
-	}
-	return nil
-}
-
@@ -0,0 +1,427 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"triplea-backend-assignment/config"
+	"triplea-backend-assignment/models"
+	"triplea-backend-assignment/ratelimit"
+	"triplea-backend-assignment/repository"
+)
+
+func newTestTransactionService(t *testing.T, balances map[int64]string) (*TransactionService, *repository.MemoryStore) {
+	t.Helper()
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	for accountID, balance := range balances {
+		if err := store.Accounts().Create(ctx, accountID, models.Decimal(balance)); err != nil {
+			t.Fatalf("failed to create account %d: %v", accountID, err)
+		}
+	}
+	return NewTransactionService(store, store.Transactions(), store.Accounts(), &fakeTransferRecorder{}, nil), store
+}
+
+type recordedTransfer struct {
+	result string
+	amount float64
+}
+
+type fakeTransferRecorder struct {
+	mu        sync.Mutex
+	transfers []recordedTransfer
+}
+
+func (r *fakeTransferRecorder) RecordTransfer(result string, amount float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transfers = append(r.transfers, recordedTransfer{result: result, amount: amount})
+}
+
+func assertBalance(t *testing.T, store *repository.MemoryStore, accountID int64, want string) {
+	t.Helper()
+	ctx := context.Background()
+	account, err := store.Accounts().GetByID(ctx, accountID)
+	if err != nil {
+		t.Fatalf("GetByID(%d) error = %v", accountID, err)
+	}
+	if account.Balance != models.Decimal(want) {
+		t.Errorf("account %d balance = %s, want %s", accountID, account.Balance, want)
+	}
+}
+
+func TestProcessTransaction_Success(t *testing.T) {
+	ctx := context.Background()
+
+	svc, store := newTestTransactionService(t, map[int64]string{1: "100", 2: "50"})
+
+	err := svc.ProcessTransaction(ctx, &models.CreateTransactionRequest{
+		SourceAccountID:      1,
+		DestinationAccountID: 2,
+		Amount:               "30.5",
+	})
+	if err != nil {
+		t.Fatalf("ProcessTransaction() error = %v", err)
+	}
+
+	assertBalance(t, store, 1, "69.5000000000")
+	assertBalance(t, store, 2, "80.5000000000")
+
+	history, err := svc.ListAccountTransactions(ctx, &models.TransactionHistoryRequest{AccountID: 1})
+	if err != nil {
+		t.Fatalf("ListAccountTransactions() error = %v", err)
+	}
+	if len(history.Transactions) != 1 || history.Transactions[0].Status != models.TransactionStatusCompleted {
+		t.Errorf("history = %+v, want one completed transaction", history.Transactions)
+	}
+}
+
+func TestProcessTransaction_InsufficientBalanceRecordsFailure(t *testing.T) {
+	ctx := context.Background()
+
+	svc, store := newTestTransactionService(t, map[int64]string{1: "10", 2: "0"})
+
+	err := svc.ProcessTransaction(ctx, &models.CreateTransactionRequest{
+		SourceAccountID:      1,
+		DestinationAccountID: 2,
+		Amount:               "25",
+	})
+	if err == nil || !strings.Contains(err.Error(), "insufficient balance") {
+		t.Fatalf("ProcessTransaction() error = %v, want insufficient balance", err)
+	}
+
+	assertBalance(t, store, 1, "10.0000000000")
+	assertBalance(t, store, 2, "0.0000000000")
+
+	history, err := svc.ListAccountTransactions(ctx, &models.TransactionHistoryRequest{
+		AccountID: 1,
+		Status:    models.TransactionStatusFailed,
+	})
+	if err != nil {
+		t.Fatalf("ListAccountTransactions() error = %v", err)
+	}
+	if len(history.Transactions) != 1 {
+		t.Fatalf("got %d failed transactions, want 1", len(history.Transactions))
+	}
+	failed := history.Transactions[0]
+	if failed.FailureReason != models.FailureReasonInsufficientFunds || failed.BalanceAtDecision != "10.0000000000" {
+		t.Errorf("failed transaction = %+v, want insufficient_funds with balance 10", failed)
+	}
+}
+
+func TestProcessTransaction_Validation(t *testing.T) {
+	ctx := context.Background()
+
+	svc, _ := newTestTransactionService(t, map[int64]string{1: "10"})
+
+	tests := []struct {
+		name    string
+		req     models.CreateTransactionRequest
+		wantErr string
+	}{
+		{
+			name:    "invalid amount",
+			req:     models.CreateTransactionRequest{SourceAccountID: 1, DestinationAccountID: 2, Amount: "-1"},
+			wantErr: "validation error",
+		},
+		{
+			name:    "missing destination account",
+			req:     models.CreateTransactionRequest{SourceAccountID: 1, DestinationAccountID: 2, Amount: "1"},
+			wantErr: "destination account 2 not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.ProcessTransaction(ctx, &tt.req)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ProcessTransaction() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcessTransaction_ConcurrentTransfersNeverOverdraw(t *testing.T) {
+	ctx := context.Background()
+
+	svc, store := newTestTransactionService(t, map[int64]string{1: "100", 2: "0"})
+
+	const attempts = 10
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := svc.ProcessTransaction(ctx, &models.CreateTransactionRequest{
+				SourceAccountID:      1,
+				DestinationAccountID: 2,
+				Amount:               "15",
+			})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 6 {
+		t.Errorf("%d transfers succeeded, want 6", succeeded)
+	}
+	assertBalance(t, store, 1, "10.0000000000")
+	assertBalance(t, store, 2, "90.0000000000")
+}
+
+func TestListAccountTransactions_Pagination(t *testing.T) {
+	ctx := context.Background()
+
+	svc, _ := newTestTransactionService(t, map[int64]string{1: "100", 2: "0"})
+
+	for i := 0; i < 3; i++ {
+		err := svc.ProcessTransaction(ctx, &models.CreateTransactionRequest{
+			SourceAccountID:      1,
+			DestinationAccountID: 2,
+			Amount:               "1",
+		})
+		if err != nil {
+			t.Fatalf("ProcessTransaction() error = %v", err)
+		}
+	}
+
+	page, err := svc.ListAccountTransactions(ctx, &models.TransactionHistoryRequest{AccountID: 2, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListAccountTransactions() error = %v", err)
+	}
+	if len(page.Transactions) != 2 || page.NextBeforeID == 0 {
+		t.Fatalf("first page = %+v, want 2 transactions and a cursor", page)
+	}
+
+	page, err = svc.ListAccountTransactions(ctx, &models.TransactionHistoryRequest{
+		AccountID: 2,
+		Limit:     2,
+		BeforeID:  page.NextBeforeID,
+	})
+	if err != nil {
+		t.Fatalf("ListAccountTransactions() error = %v", err)
+	}
+	if len(page.Transactions) != 1 || page.NextBeforeID != 0 {
+		t.Errorf("second page = %+v, want 1 transaction and no cursor", page)
+	}
+
+	if _, err := svc.ListAccountTransactions(ctx, &models.TransactionHistoryRequest{AccountID: 3}); err == nil ||
+		!strings.Contains(err.Error(), "account not found") {
+		t.Errorf("ListAccountTransactions() for missing account error = %v, want account not found", err)
+	}
+}
+
+func TestProcessTransaction_ContextDone(t *testing.T) {
+	ctx := context.Background()
+
+	svc, store := newTestTransactionService(t, map[int64]string{1: "100", 2: "0"})
+	req := &models.CreateTransactionRequest{SourceAccountID: 1, DestinationAccountID: 2, Amount: "10"}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := svc.ProcessTransaction(canceled, req); !errors.Is(err, context.Canceled) {
+		t.Errorf("ProcessTransaction() with canceled context error = %v, want context.Canceled", err)
+	}
+
+	// A Tx held elsewhere keeps the transfer waiting until its deadline.
+	holder, err := store.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	expiring, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := svc.ProcessTransaction(expiring, req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ProcessTransaction() past deadline error = %v, want context.DeadlineExceeded", err)
+	}
+	if err := holder.Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+
+	assertBalance(t, store, 1, "100.0000000000")
+	assertBalance(t, store, 2, "0.0000000000")
+}
+
+func TestProcessTransaction_RecordsResult(t *testing.T) {
+	tests := []struct {
+		name string
+		req  models.CreateTransactionRequest
+		want recordedTransfer
+	}{
+		{name: "completed", req: models.CreateTransactionRequest{SourceAccountID: 1, DestinationAccountID: 2, Amount: "30"},
+			want: recordedTransfer{result: TransferResultCompleted, amount: 30}},
+		{name: "insufficient funds", req: models.CreateTransactionRequest{SourceAccountID: 1, DestinationAccountID: 2, Amount: "500"},
+			want: recordedTransfer{result: TransferResultInsufficientFunds, amount: 500}},
+		{name: "unknown account", req: models.CreateTransactionRequest{SourceAccountID: 1, DestinationAccountID: 9, Amount: "5"},
+			want: recordedTransfer{result: TransferResultAccountNotFound, amount: 5}},
+		{name: "invalid amount", req: models.CreateTransactionRequest{SourceAccountID: 1, DestinationAccountID: 2, Amount: "-5"},
+			want: recordedTransfer{result: TransferResultInvalid, amount: -5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := repository.NewMemoryStore()
+			for accountID, balance := range map[int64]string{1: "100", 2: "50"} {
+				if err := store.Accounts().Create(ctx, accountID, models.Decimal(balance)); err != nil {
+					t.Fatalf("failed to create account %d: %v", accountID, err)
+				}
+			}
+			recorder := &fakeTransferRecorder{}
+			svc := NewTransactionService(store, store.Transactions(), store.Accounts(), recorder, nil)
+
+			svc.ProcessTransaction(ctx, &tt.req)
+
+			if len(recorder.transfers) != 1 || recorder.transfers[0] != tt.want {
+				t.Errorf("recorded %+v, want %+v", recorder.transfers, tt.want)
+			}
+		})
+	}
+}
+
+// lockRecorder records the order in which accounts are locked.
+type lockRecorder struct {
+	repository.AccountRepository
+	locked []int64
+}
+
+func (r *lockRecorder) GetByIDWithLock(ctx context.Context, tx repository.Tx, accountID int64) (*models.Account, error) {
+	r.locked = append(r.locked, accountID)
+	return r.AccountRepository.GetByIDWithLock(ctx, tx, accountID)
+}
+
+func TestProcessTransaction_LocksAccountsInIDOrder(t *testing.T) {
+	for _, tt := range []struct {
+		source, destination int64
+	}{{1, 2}, {2, 1}} {
+		t.Run(fmt.Sprintf("%d to %d", tt.source, tt.destination), func(t *testing.T) {
+			ctx := context.Background()
+			store := repository.NewMemoryStore()
+			for _, accountID := range []int64{1, 2} {
+				if err := store.Accounts().Create(ctx, accountID, "100"); err != nil {
+					t.Fatalf("failed to create account %d: %v", accountID, err)
+				}
+			}
+			accounts := &lockRecorder{AccountRepository: store.Accounts()}
+			svc := NewTransactionService(store, store.Transactions(), accounts, &fakeTransferRecorder{}, nil)
+
+			err := svc.ProcessTransaction(ctx, &models.CreateTransactionRequest{
+				SourceAccountID: tt.source, DestinationAccountID: tt.destination, Amount: "30"})
+			if err != nil {
+				t.Fatalf("ProcessTransaction() error = %v", err)
+			}
+			if len(accounts.locked) != 2 || accounts.locked[0] != 1 || accounts.locked[1] != 2 {
+				t.Errorf("locked accounts %v, want [1 2]", accounts.locked)
+			}
+			assertBalance(t, store, tt.source, "70.0000000000")
+			assertBalance(t, store, tt.destination, "130.0000000000")
+		})
+	}
+}
+
+func TestProcessTransaction_LimitsDebitsPerAccount(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	for accountID, balance := range map[int64]string{1: "100", 2: "100", 3: "0"} {
+		if err := store.Accounts().Create(ctx, accountID, models.Decimal(balance)); err != nil {
+			t.Fatalf("failed to create account %d: %v", accountID, err)
+		}
+	}
+	recorder := &fakeTransferRecorder{}
+	debits := ratelimit.NewLimiter(config.RateLimit{Requests: 2, Per: time.Hour})
+	svc := NewTransactionService(store, store.Transactions(), store.Accounts(), recorder, debits)
+
+	transfer := func(source, destination int64) error {
+		return svc.ProcessTransaction(ctx, &models.CreateTransactionRequest{
+			SourceAccountID: source, DestinationAccountID: destination, Amount: "10"})
+	}
+	for i := 0; i < 2; i++ {
+		if err := transfer(1, 3); err != nil {
+			t.Fatalf("debit #%d error = %v", i+1, err)
+		}
+	}
+	err := transfer(1, 2)
+	var limited *ratelimit.ExceededError
+	if !errors.As(err, &limited) {
+		t.Fatalf("third debit error = %v, want a rate limit error", err)
+	}
+	if limited.Decision.RetryAfter <= 0 {
+		t.Errorf("RetryAfter = %v, want a positive duration", limited.Decision.RetryAfter)
+	}
+	if got := recorder.transfers[len(recorder.transfers)-1].result; got != TransferResultRateLimited {
+		t.Errorf("recorded result = %s, want %s", got, TransferResultRateLimited)
+	}
+	assertBalance(t, store, 1, "80.0000000000")
+
+	// Credits do not count against the limit, and other accounts have their own.
+	if err := transfer(2, 1); err != nil {
+		t.Errorf("debit from another account error = %v", err)
+	}
+}
+
+func TestProcessTransaction_TracesPhases(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       models.CreateTransactionRequest
+		wantSpans []string
+		wantError string
+	}{
+		{name: "completed", req: models.CreateTransactionRequest{SourceAccountID: 1, DestinationAccountID: 2, Amount: "30"},
+			wantSpans: []string{"transfer.validate", "transfer.lock", "transfer.update", "transfer.commit", "TransactionService.ProcessTransaction"}},
+		{name: "invalid amount", req: models.CreateTransactionRequest{SourceAccountID: 1, DestinationAccountID: 2, Amount: "-5"},
+			wantSpans: []string{"transfer.validate", "TransactionService.ProcessTransaction"}, wantError: "transfer.validate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans := recordSpans(t)
+			svc, _ := newTestTransactionService(t, map[int64]string{1: "100", 2: "50"})
+
+			svc.ProcessTransaction(context.Background(), &tt.req)
+
+			ended := spans.Ended()
+			if len(ended) != len(tt.wantSpans) {
+				t.Fatalf("got %d spans, want %v", len(ended), tt.wantSpans)
+			}
+			parent := ended[len(ended)-1]
+			for i, span := range ended {
+				if span.Name() != tt.wantSpans[i] {
+					t.Errorf("span %d = %q, want %q", i, span.Name(), tt.wantSpans[i])
+				}
+				if span != parent && span.Parent().SpanID() != parent.SpanContext().SpanID() {
+					t.Errorf("span %q is not a child of %q", span.Name(), parent.Name())
+				}
+				wantFailed := tt.wantError != "" && (span.Name() == tt.wantError || span == parent)
+				if failed := span.Status().Code == codes.Error; failed != wantFailed {
+					t.Errorf("span %q status = %v, want failed %v", span.Name(), span.Status(), wantFailed)
+				}
+			}
+		})
+	}
+}
+
+// recordSpans installs a tracer provider that records spans until the test
+// ends.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}